@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry describes one cached track.
+type Entry struct {
+	URL          string    `json:"url"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	LastUsed     time.Time `json:"last_used"`
+}
+
+// Cache is an on-disk LRU cache of downloaded tracks keyed by URL. Once the
+// total size passes the cap, the least recently used tracks are evicted.
+// A nil *Cache is valid and caches nothing.
+type Cache struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	size    int64
+	entries map[string]*Entry // keyed by URL
+}
+
+// DefaultDir returns the track cache directory under the user cache dir.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "dopogoto", "tracks")
+}
+
+// New opens (or creates) a cache in dir capped at maxSize bytes. It returns
+// nil if maxSize is not positive.
+func New(dir string, maxSize int64) (*Cache, error) {
+	if maxSize <= 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[string]*Entry),
+	}
+	c.loadIndex()
+	c.mu.Lock()
+	c.evict("")
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Lookup returns the entry for url without marking it used.
+func (c *Cache) Lookup(url string) (Entry, bool) {
+	if c == nil {
+		return Entry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Read returns the cached bytes for url and marks it most recently used.
+func (c *Cache) Read(url string) ([]byte, error) {
+	if c == nil {
+		return nil, os.ErrNotExist
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(c.path(url))
+	if err != nil || int64(len(data)) != e.Size {
+		c.remove(url)
+		c.saveIndex()
+		if err == nil {
+			err = os.ErrNotExist
+		}
+		return nil, err
+	}
+	e.LastUsed = time.Now()
+	c.saveIndex()
+	return data, nil
+}
+
+// Put stores data for url along with its HTTP validators, evicting older
+// tracks to stay under the size cap. Tracks larger than the cap are skipped.
+func (c *Cache) Put(url string, data []byte, etag, lastModified string) error {
+	if c == nil || int64(len(data)) > c.maxSize {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Write to a temp file first so a crash never leaves a truncated track.
+	tmp, err := os.CreateTemp(c.dir, "dl-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(url)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if old, ok := c.entries[url]; ok {
+		c.size -= old.Size
+	}
+	c.entries[url] = &Entry{
+		URL:          url,
+		Size:         int64(len(data)),
+		ETag:         etag,
+		LastModified: lastModified,
+		LastUsed:     time.Now(),
+	}
+	c.size += int64(len(data))
+	c.evict(url)
+	return c.saveIndex()
+}
+
+// Touch marks url as most recently used.
+func (c *Cache) Touch(url string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[url]; ok {
+		e.LastUsed = time.Now()
+		c.saveIndex()
+	}
+}
+
+// Size returns the total bytes currently cached.
+func (c *Cache) Size() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// evict drops least recently used entries until the cache fits, never
+// evicting keep. Caller must hold c.mu.
+func (c *Cache) evict(keep string) {
+	for c.size > c.maxSize {
+		var oldest *Entry
+		for _, e := range c.entries {
+			if e.URL == keep {
+				continue
+			}
+			if oldest == nil || e.LastUsed.Before(oldest.LastUsed) {
+				oldest = e
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		c.remove(oldest.URL)
+	}
+}
+
+// remove deletes url's file and entry. Caller must hold c.mu.
+func (c *Cache) remove(url string) {
+	e, ok := c.entries[url]
+	if !ok {
+		return
+	}
+	os.Remove(c.path(url))
+	c.size -= e.Size
+	delete(c.entries, url)
+}
+
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".mp3")
+}
+
+func (c *Cache) indexPath() string {
+	return filepath.Join(c.dir, "index.json")
+}
+
+// loadIndex reads the index, dropping entries whose files have gone missing.
+func (c *Cache) loadIndex() {
+	data, err := os.ReadFile(c.indexPath())
+	if err != nil {
+		return
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range entries {
+		fi, err := os.Stat(c.path(e.URL))
+		if err != nil || fi.Size() != e.Size {
+			continue
+		}
+		c.entries[e.URL] = e
+		c.size += e.Size
+	}
+}
+
+// saveIndex persists the index. Caller must hold c.mu.
+func (c *Cache) saveIndex() error {
+	entries := make([]*Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp := c.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.indexPath())
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPutRead(t *testing.T) {
+	c, err := New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("mp3 bytes")
+	if err := c.Put("https://cdn/a.mp3", data, `"v1"`, "Mon, 02 Jan 2006 15:04:05 GMT"); err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := c.Lookup("https://cdn/a.mp3")
+	if !ok {
+		t.Fatal("Lookup: entry missing after Put")
+	}
+	if e.ETag != `"v1"` || e.LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("validators = %q, %q", e.ETag, e.LastModified)
+	}
+	got, err := c.Read("https://cdn/a.mp3")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Read = %q, %v; want %q", got, err, data)
+	}
+	if _, err := c.Read("https://cdn/missing.mp3"); err == nil {
+		t.Error("Read of missing URL should fail")
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := New(t.TempDir(), 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := bytes.Repeat([]byte{1}, 10)
+	c.Put("a", chunk, "", "")
+	time.Sleep(2 * time.Millisecond)
+	c.Put("b", chunk, "", "")
+	time.Sleep(2 * time.Millisecond)
+	c.Touch("a") // a is now newer than b
+	time.Sleep(2 * time.Millisecond)
+	c.Put("c", chunk, "", "")
+
+	if _, ok := c.Lookup("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, url := range []string{"a", "c"} {
+		if _, ok := c.Lookup(url); !ok {
+			t.Errorf("%s should still be cached", url)
+		}
+	}
+	if c.Size() != 20 {
+		t.Errorf("Size() = %d, want 20", c.Size())
+	}
+}
+
+func TestSkipsOversizedTracks(t *testing.T) {
+	c, err := New(t.TempDir(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("big", make([]byte, 6), "", "")
+	if _, ok := c.Lookup("big"); ok {
+		t.Error("track larger than the cap should not be cached")
+	}
+}
+
+func TestIndexPersists(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", []byte("abc"), `"x"`, "")
+
+	reopened, err := New(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := reopened.Lookup("a")
+	if !ok || e.ETag != `"x"` || reopened.Size() != 3 {
+		t.Errorf("reopened cache: entry=%+v ok=%v size=%d", e, ok, reopened.Size())
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	if _, ok := c.Lookup("a"); ok {
+		t.Error("nil cache should miss")
+	}
+	if err := c.Put("a", []byte("x"), "", ""); err != nil {
+		t.Errorf("nil cache Put = %v", err)
+	}
+	if c, err := New(t.TempDir(), 0); c != nil || err != nil {
+		t.Errorf("New with zero cap = %v, %v; want nil, nil", c, err)
+	}
+}
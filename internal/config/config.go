@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Config holds player settings persisted between sessions.
+type Config struct {
+	CacheMB    int       `json:"cache_mb"`   // track cache size cap; 0 disables the cache
+	Crossfade  int       `json:"crossfade"`  // seconds consecutive tracks overlap, 0-12; 0 is gapless
+	Normalize  string    `json:"normalize"`  // loudness normalization: "off", "track" or "album"
+	EQPreset   string    `json:"eq_preset"`  // equalizer preset name, "" for custom gains
+	EQ         []float64 `json:"eq"`         // equalizer band gains in dB, lowest band first
+	Visualizer string    `json:"visualizer"` // spectrum bars: "off", "replace" (instead of the video) or "overlay" (over it)
+	SpeedMode  string    `json:"speed_mode"` // how playback speed changes: "stretch" keeps the pitch, "tape" doesn't
+	SleepQuit  bool      `json:"sleep_quit"` // quit when the sleep timer goes off instead of just pausing
+	Shuffle    string    `json:"shuffle"`    // what shuffle draws from: "all" albums, the playing track's "genre" or its "album"
+	Library    []string  `json:"library"`    // folders of local music listed after the catalog
+}
+
+// Default returns the settings used when nothing has been saved yet.
+func Default() Config {
+	return Config{
+		CacheMB:    1024,
+		Normalize:  "off",
+		EQPreset:   "Flat",
+		Visualizer: "off",
+		SpeedMode:  "stretch",
+		Shuffle:    "all",
+	}
+}
+
+// Dir returns the dopogoto config directory.
+func Dir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".config", "dopogoto")
+}
+
+var configPath = filepath.Join(Dir(), "settings.json")
+
+// Load reads the settings file. Missing or invalid fields keep their defaults.
+func Load() Config {
+	cfg := Default()
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Default()
+	}
+	if cfg.Crossfade < 0 || cfg.Crossfade > 12 {
+		cfg.Crossfade = Default().Crossfade
+	}
+	switch cfg.Normalize {
+	case "off", "track", "album":
+	default:
+		cfg.Normalize = Default().Normalize
+	}
+	switch cfg.Visualizer {
+	case "off", "replace", "overlay":
+	default:
+		cfg.Visualizer = Default().Visualizer
+	}
+	switch cfg.SpeedMode {
+	case "stretch", "tape":
+	default:
+		cfg.SpeedMode = Default().SpeedMode
+	}
+	switch cfg.Shuffle {
+	case "all", "genre", "album":
+	default:
+		cfg.Shuffle = Default().Shuffle
+	}
+	return cfg
+}
+
+// Save persists the settings.
+func Save(cfg Config) error {
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, data, 0644)
+}
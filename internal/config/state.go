@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// State is where the last session left off. Albums are shuffled on every
+// launch, so the track is kept by album title and track URL rather than by
+// list position.
+type State struct {
+	Album    string `json:"album"`    // album title, "" if nothing was played
+	Track    string `json:"track"`    // track URL
+	Position int    `json:"position"` // seconds into the track
+	Volume   int    `json:"volume"`   // 0-10
+	Shuffle  bool   `json:"shuffle"`
+	Repeat   Repeat `json:"repeat"`
+	Theme    string `json:"theme"`           // theme name
+	Genre    string `json:"genre,omitempty"` // album list genre filter
+}
+
+// Repeat is the repeat mode: "off", "one", "album" or "all". Earlier
+// versions saved a bool, read as "one" or "off".
+type Repeat string
+
+func (r *Repeat) UnmarshalJSON(data []byte) error {
+	var on bool
+	if json.Unmarshal(data, &on) == nil {
+		*r = "off"
+		if on {
+			*r = "one"
+		}
+		return nil
+	}
+	var mode string
+	if err := json.Unmarshal(data, &mode); err != nil {
+		return err
+	}
+	*r = Repeat(mode)
+	return nil
+}
+
+var statePath = filepath.Join(Dir(), "state.json")
+
+// LoadState reads the state saved when the app last quit. ok is false if
+// there is none.
+func LoadState() (st State, ok bool) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return State{}, false
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return State{}, false
+	}
+	if st.Position < 0 {
+		st.Position = 0
+	}
+	st.Volume = min(max(st.Volume, 0), 10)
+	return st, true
+}
+
+// SaveState records where the session left off.
+func SaveState(st State) error {
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath, data, 0644)
+}
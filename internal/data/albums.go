@@ -18,6 +18,19 @@ type Album struct {
 	Tracks []Track
 }
 
+// Duration returns the album's total length, and whether every track's
+// length is known.
+func (a Album) Duration() (time.Duration, bool) {
+	var total time.Duration
+	for _, t := range a.Tracks {
+		if t.Duration <= 0 {
+			return total, false
+		}
+		total += t.Duration
+	}
+	return total, len(a.Tracks) > 0
+}
+
 // trackURL builds a CDN URL for a standard Dopo Goto track.
 func trackURL(album string, num int, title string) string {
 	return fmt.Sprintf("https://cdn.dopogoto.com/Dopo Goto - %s/Dopo Goto - %s - %02d %s.mp3", album, album, num, title)
@@ -383,6 +396,43 @@ func ShuffledAlbums() []Album {
 	return shuffled
 }
 
+// genreNames spells out the catalog's genre codes.
+var genreNames = map[string]string{
+	"AMB": "Ambient",
+	"DNB": "Drum & Bass",
+	"JNG": "Jungle",
+	"IDM": "IDM",
+	"BRC": "Breakcore",
+}
+
+// GenreName returns the full name of a catalog genre code, or the code
+// itself for any other genre.
+func GenreName(code string) string {
+	if name, ok := genreNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// IsGenre reports whether code is one of the catalog's genres.
+func IsGenre(code string) bool {
+	_, ok := genreNames[code]
+	return ok
+}
+
+// Genres returns the catalog's genre codes in the order they first appear.
+func Genres() []string {
+	var genres []string
+	seen := map[string]bool{}
+	for _, album := range Albums {
+		if !seen[album.Genre] {
+			seen[album.Genre] = true
+			genres = append(genres, album.Genre)
+		}
+	}
+	return genres
+}
+
 // FormatDuration formats a duration as M:SS or H:MM:SS.
 func FormatDuration(d time.Duration) string {
 	total := int(d.Seconds())
@@ -40,6 +40,9 @@ func TestAlbumCatalog(t *testing.T) {
 		if len(album.Tracks) == 0 {
 			t.Errorf("album %q has no tracks", album.Title)
 		}
+		if !IsGenre(album.Genre) {
+			t.Errorf("album %q has unnamed genre %q", album.Title, album.Genre)
+		}
 		for j, track := range album.Tracks {
 			if track.Title == "" {
 				t.Errorf("album %q track %d has empty title", album.Title, j)
@@ -50,3 +53,19 @@ func TestAlbumCatalog(t *testing.T) {
 		}
 	}
 }
+
+func TestGenres(t *testing.T) {
+	got := Genres()
+	want := []string{"AMB", "DNB", "JNG", "IDM", "BRC"}
+	if len(got) != len(want) {
+		t.Fatalf("Genres() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Genres()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if got := GenreName("Local"); got != "Local" {
+		t.Errorf("GenreName(%q) = %q, want it unchanged", "Local", got)
+	}
+}
@@ -0,0 +1,50 @@
+// Package dsp holds small signal-processing building blocks shared by the
+// player: filters, loudness measurement and the FFT.
+package dsp
+
+import "math"
+
+// Biquad is a second-order IIR filter in transposed direct form II, with
+// coefficients normalized so a0 is 1. The zero value passes nothing; build
+// one with the constructors below or fill in the coefficients.
+type Biquad struct {
+	B0, B1, B2 float64
+	A1, A2     float64
+	z1, z2     float64
+}
+
+// Process filters one sample.
+func (f *Biquad) Process(x float64) float64 {
+	y := f.B0*x + f.z1
+	f.z1 = f.B1*x - f.A1*y + f.z2
+	f.z2 = f.B2*x - f.A2*y
+	return y
+}
+
+// Reset clears the filter's state, e.g. after a seek.
+func (f *Biquad) Reset() {
+	f.z1, f.z2 = 0, 0
+}
+
+// Peaking returns a bell filter boosting or cutting gainDB around freq Hz
+// (RBJ audio EQ cookbook).
+func Peaking(rate, freq, q, gainDB float64) Biquad {
+	a := math.Pow(10, gainDB/40)
+	w0 := 2 * math.Pi * freq / rate
+	alpha := math.Sin(w0) / (2 * q)
+	cos := math.Cos(w0)
+	a0 := 1 + alpha/a
+	return Biquad{
+		B0: (1 + alpha*a) / a0,
+		B1: -2 * cos / a0,
+		B2: (1 - alpha*a) / a0,
+		A1: -2 * cos / a0,
+		A2: (1 - alpha/a) / a0,
+	}
+}
+
+// Retune switches to n's coefficients, keeping the filter's state so a
+// change while playing doesn't click.
+func (f *Biquad) Retune(n Biquad) {
+	f.B0, f.B1, f.B2, f.A1, f.A2 = n.B0, n.B1, n.B2, n.A1, n.A2
+}
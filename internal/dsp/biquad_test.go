@@ -0,0 +1,43 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// gainAt measures a filter's steady-state gain in dB for a sine at freq.
+func gainAt(f Biquad, rate, freq float64) float64 {
+	var peak float64
+	n := int(rate) // one second; the last half is steady state
+	for i := 0; i < n; i++ {
+		y := f.Process(math.Sin(2 * math.Pi * freq * float64(i) / rate))
+		if i > n/2 {
+			peak = math.Max(peak, math.Abs(y))
+		}
+	}
+	return 20 * math.Log10(peak)
+}
+
+func TestPeaking(t *testing.T) {
+	tests := []struct {
+		name   string
+		gainDB float64
+		freq   float64 // probe frequency
+		want   float64
+		tol    float64
+	}{
+		{"boost at center", 6, 1000, 6, 0.1},
+		{"cut at center", -9, 1000, -9, 0.1},
+		{"far below untouched", 6, 40, 0, 0.2},
+		{"far above untouched", 6, 16000, 0, 0.3},
+		{"flat", 0, 1000, 0, 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Peaking(44100, 1000, 1.41, tt.gainDB)
+			if got := gainAt(f, 44100, tt.freq); math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("gain at %v Hz = %.2f dB, want %.2f", tt.freq, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func TestFFTMatchesDFT(t *testing.T) {
+	for _, n := range []int{1, 2, 8, 64} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(rand.Float64()*2-1, rand.Float64()*2-1)
+		}
+		want := make([]complex128, n)
+		for k := range want {
+			for i, v := range x {
+				want[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*i)/float64(n)))
+			}
+		}
+
+		FFT(x)
+		for k := range x {
+			if cmplx.Abs(x[k]-want[k]) > 1e-9 {
+				t.Fatalf("n=%d bin %d = %v, want %v", n, k, x[k], want[k])
+			}
+		}
+	}
+}
+
+func TestFFTSine(t *testing.T) {
+	const n, bin = 1024, 37
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(math.Sin(2*math.Pi*bin*float64(i)/n), 0)
+	}
+	FFT(x)
+	for k := 0; k < n/2; k++ {
+		mag := cmplx.Abs(x[k]) * 2 / n
+		if k == bin && math.Abs(mag-1) > 1e-9 {
+			t.Errorf("bin %d magnitude = %v, want 1", k, mag)
+		}
+		if k != bin && mag > 1e-9 {
+			t.Errorf("leakage into bin %d: %v", k, mag)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package dsp
+
+import (
+	"math"
+)
+
+// Meter measures integrated loudness per EBU R128 / ITU-R BS.1770: the
+// signal is K-weighted, cut into 400 ms blocks overlapping by 75%, and the
+// blocks are gated at -70 LUFS and then 10 LU below their own average.
+type Meter struct {
+	filters [2][2]Biquad // per channel: pre-filter shelf, then RLB high-pass
+	subLen  int          // samples per 100 ms sub-block
+	acc     float64      // energy summed over the current sub-block
+	n       int          // samples in the current sub-block
+	subs    []float64    // mean-square energy of each finished sub-block
+	peak    float64
+	total   int
+	rate    int
+}
+
+// NewMeter returns a meter for stereo audio at rate Hz.
+func NewMeter(rate int) *Meter {
+	m := &Meter{subLen: rate / 10, rate: rate}
+	for c := range m.filters {
+		m.filters[c] = kWeighting(float64(rate))
+	}
+	return m
+}
+
+// kWeighting returns the BS.1770 pre-filter and RLB filter designed for the
+// given rate (coefficients as derived in libebur128).
+func kWeighting(rate float64) [2]Biquad {
+	// High shelf, +4 dB above ~1.7 kHz
+	f0, g, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	pre := Biquad{
+		B0: (vh + vb*k/q + k*k) / a0,
+		B1: 2 * (k*k - vh) / a0,
+		B2: (vh - vb*k/q + k*k) / a0,
+		A1: 2 * (k*k - 1) / a0,
+		A2: (1 - k/q + k*k) / a0,
+	}
+
+	// High-pass at ~38 Hz
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+	rlb := Biquad{
+		B0: 1, B1: -2, B2: 1,
+		A1: 2 * (k*k - 1) / a0,
+		A2: (1 - k/q + k*k) / a0,
+	}
+	return [2]Biquad{pre, rlb}
+}
+
+// Write feeds samples to the meter.
+func (m *Meter) Write(samples [][2]float64) {
+	for _, s := range samples {
+		var e float64
+		for c := range s {
+			if a := math.Abs(s[c]); a > m.peak {
+				m.peak = a
+			}
+			y := m.filters[c][1].Process(m.filters[c][0].Process(s[c]))
+			e += y * y
+		}
+		m.acc += e
+		m.n++
+		if m.n == m.subLen {
+			m.subs = append(m.subs, m.acc/float64(m.subLen))
+			m.acc, m.n = 0, 0
+		}
+	}
+	m.total += len(samples)
+}
+
+// Loudness returns the integrated loudness in LUFS. ok is false if nothing
+// rose above the absolute gate (silence, or under 400 ms of audio).
+func (m *Meter) Loudness() (lufs float64, ok bool) {
+	var blocks []float64
+	for i := 0; i+4 <= len(m.subs); i++ {
+		e := (m.subs[i] + m.subs[i+1] + m.subs[i+2] + m.subs[i+3]) / 4
+		if energyLUFS(e) > -70 {
+			blocks = append(blocks, e)
+		}
+	}
+	if len(blocks) == 0 {
+		return 0, false
+	}
+	threshold := energyLUFS(mean(blocks)) - 10
+	var gated []float64
+	for _, e := range blocks {
+		if energyLUFS(e) > threshold {
+			gated = append(gated, e)
+		}
+	}
+	return energyLUFS(mean(gated)), true
+}
+
+// Peak returns the highest absolute sample value seen.
+func (m *Meter) Peak() float64 {
+	return m.peak
+}
+
+// Seconds returns how much audio has been written.
+func (m *Meter) Seconds() float64 {
+	return float64(m.total) / float64(m.rate)
+}
+
+func energyLUFS(e float64) float64 {
+	return -0.691 + 10*math.Log10(e)
+}
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
@@ -0,0 +1,66 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns seconds of a stereo sine at freq Hz with peak amplitude dbfs.
+func sine(rate int, freq, dbfs, seconds float64) [][2]float64 {
+	amp := math.Pow(10, dbfs/20)
+	out := make([][2]float64, int(float64(rate)*seconds))
+	for i := range out {
+		v := amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+		out[i] = [2]float64{v, v}
+	}
+	return out
+}
+
+func TestMeterLoudness(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    int
+		signal  [][2]float64
+		want    float64
+		wantOK  bool
+		maxDiff float64
+	}{
+		// EBU Tech 3341 test 1 and 2: 1 kHz stereo sine at -23 and -33 dBFS.
+		{"-23 dBFS 48k", 48000, sine(48000, 1000, -23, 20), -23, true, 0.1},
+		{"-33 dBFS 48k", 48000, sine(48000, 1000, -33, 20), -33, true, 0.1},
+		{"-23 dBFS 44.1k", 44100, sine(44100, 1000, -23, 20), -23, true, 0.1},
+		// Silence between tones is gated out.
+		{"gated silence", 48000, append(sine(48000, 1000, -23, 10), make([][2]float64, 48000*10)...), -23, true, 0.1},
+		{"silence", 48000, make([][2]float64, 48000*5), 0, false, 0},
+		{"too short", 48000, sine(48000, 1000, -23, 0.3), 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMeter(tt.rate)
+			// Feed in odd-sized chunks to exercise sub-block boundaries.
+			for s := tt.signal; len(s) > 0; {
+				n := min(len(s), 1000)
+				m.Write(s[:n])
+				s = s[n:]
+			}
+			got, ok := m.Loudness()
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && math.Abs(got-tt.want) > tt.maxDiff {
+				t.Errorf("loudness = %.2f LUFS, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeterPeak(t *testing.T) {
+	m := NewMeter(48000)
+	m.Write(sine(48000, 1000, -6, 1))
+	if got, want := m.Peak(), math.Pow(10, -6.0/20); math.Abs(got-want) > 1e-3 {
+		t.Errorf("peak = %.4f, want %.4f", got, want)
+	}
+	if got := m.Seconds(); got != 1 {
+		t.Errorf("seconds = %v, want 1", got)
+	}
+}
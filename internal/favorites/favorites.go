@@ -0,0 +1,114 @@
+// Package favorites remembers which tracks the user has favorited and how
+// they've rated them, keyed by track URL.
+package favorites
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/config"
+)
+
+// MaxRating is the most stars a track can be given.
+const MaxRating = 5
+
+// Mark is what the user has said about a track.
+type Mark struct {
+	Favorite bool      `json:"favorite,omitempty"`
+	Rating   int       `json:"rating,omitempty"` // 1 to MaxRating, 0 if unrated
+	Added    time.Time `json:"added,omitzero"`   // when it was favorited
+}
+
+// Store maps track URLs to their marks and persists them as JSON.
+type Store struct {
+	path  string
+	marks map[string]Mark
+}
+
+// DefaultPath returns the store's file in the config dir.
+func DefaultPath() string {
+	return filepath.Join(config.Dir(), "favorites.json")
+}
+
+// Open loads the store at path. A missing or unreadable file starts empty.
+func Open(path string) *Store {
+	s := &Store{path: path, marks: make(map[string]Mark)}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &s.marks)
+	}
+	return s
+}
+
+// Get returns the mark for url, zero if there is none.
+func (s *Store) Get(url string) Mark {
+	return s.marks[url]
+}
+
+// ToggleFavorite favorites or unfavorites url and returns whether it's now
+// a favorite.
+func (s *Store) ToggleFavorite(url string, now time.Time) bool {
+	m := s.marks[url]
+	m.Favorite = !m.Favorite
+	m.Added = time.Time{}
+	if m.Favorite {
+		m.Added = now
+	}
+	s.set(url, m)
+	return m.Favorite
+}
+
+// Rate gives url stars out of MaxRating, clamped; 0 clears the rating.
+func (s *Store) Rate(url string, stars int) {
+	m := s.marks[url]
+	m.Rating = max(0, min(stars, MaxRating))
+	s.set(url, m)
+}
+
+func (s *Store) set(url string, m Mark) {
+	if m == (Mark{}) {
+		delete(s.marks, url)
+		return
+	}
+	s.marks[url] = m
+}
+
+// Favorites returns the favorited URLs, highest rated first, then in the
+// order they were favorited.
+func (s *Store) Favorites() []string {
+	var urls []string
+	for url, m := range s.marks {
+		if m.Favorite {
+			urls = append(urls, url)
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		a, b := s.marks[urls[i]], s.marks[urls[j]]
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		if !a.Added.Equal(b.Added) {
+			return a.Added.Before(b.Added)
+		}
+		return urls[i] < urls[j]
+	})
+	return urls
+}
+
+// Save writes the store.
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
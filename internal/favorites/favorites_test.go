@@ -0,0 +1,65 @@
+package favorites
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFavoritesOrder(t *testing.T) {
+	s := Open(filepath.Join(t.TempDir(), "favorites.json"))
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, url := range []string{"a", "b", "c", "d"} {
+		s.ToggleFavorite(url, start.Add(time.Duration(i)*time.Minute))
+	}
+	s.Rate("c", 5)
+	s.Rate("d", 3)
+	s.Rate("e", 4) // rated but not a favorite
+	s.ToggleFavorite("b", start)
+
+	if got, want := s.Favorites(), []string{"c", "d", "a"}; !slices.Equal(got, want) {
+		t.Errorf("Favorites() = %q, want %q", got, want)
+	}
+}
+
+func TestMarks(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(s *Store)
+		want Mark
+	}{
+		{"rate", func(s *Store) { s.Rate("x", 3) }, Mark{Rating: 3}},
+		{"rate clamps", func(s *Store) { s.Rate("x", 9) }, Mark{Rating: MaxRating}},
+		{"clear rating", func(s *Store) { s.Rate("x", 3); s.Rate("x", 0) }, Mark{}},
+		{"unfavorite keeps rating", func(s *Store) {
+			s.Rate("x", 2)
+			s.ToggleFavorite("x", time.Now())
+			s.ToggleFavorite("x", time.Now())
+		}, Mark{Rating: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Open(filepath.Join(t.TempDir(), "favorites.json"))
+			tt.op(s)
+			if got := s.Get("x"); got != tt.want {
+				t.Errorf("Get() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "favorites.json")
+	s := Open(path)
+	added := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.ToggleFavorite("https://cdn/x.mp3", added)
+	s.Rate("https://cdn/x.mp3", 4)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	want := Mark{Favorite: true, Rating: 4, Added: added}
+	if got := Open(path).Get("https://cdn/x.mp3"); !got.Added.Equal(want.Added) || got.Favorite != want.Favorite || got.Rating != want.Rating {
+		t.Errorf("reopened store has %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+// Package history records every play in an append-only log and sums it up
+// into listening statistics.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/config"
+)
+
+// Play is one listen of a track.
+type Play struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Album     string    `json:"album"`
+	Start     time.Time `json:"start"`
+	Seconds   float64   `json:"seconds"`   // time actually spent listening
+	Completed bool      `json:"completed"` // played to the end rather than skipped
+}
+
+// DefaultPath returns the log's file in the config dir.
+func DefaultPath() string {
+	return filepath.Join(config.Dir(), "history.jsonl")
+}
+
+// Append adds p to the end of the log at path, one JSON object per line.
+func Append(path string, p Play) error {
+	line, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the log at path, oldest play first. A missing log is empty;
+// lines that don't parse, such as one cut short by a crash, are skipped.
+func Load(path string) ([]Play, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var plays []Play
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var p Play
+		if json.Unmarshal(sc.Bytes(), &p) == nil && p.URL != "" {
+			plays = append(plays, p)
+		}
+	}
+	return plays, sc.Err()
+}
@@ -0,0 +1,83 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if plays, err := Load(path); err != nil || plays != nil {
+		t.Fatalf("Load(missing) = %v, %v; want nil, nil", plays, err)
+	}
+	start := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	want := []Play{
+		{URL: "https://cdn/a.mp3", Title: "A", Album: "One", Start: start, Seconds: 181.5, Completed: true},
+		{URL: "https://cdn/b.mp3", Title: "B", Album: "One", Start: start.Add(3 * time.Minute), Seconds: 12},
+	}
+	for _, p := range want {
+		if err := Append(path, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A line cut short by a crash is skipped
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"url":"https://cdn/c.mp3","tit`)
+	f.Close()
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Load() returned %d plays, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || got[i].URL != want[i].URL || got[i].Seconds != want[i].Seconds || got[i].Completed != want[i].Completed {
+			t.Errorf("play %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo) }
+	plays := []Play{
+		// A run of three days a week ago
+		{URL: "a", Title: "A", Album: "One", Start: at(9), Seconds: 200, Completed: true},
+		{URL: "a", Title: "A", Album: "One", Start: at(8), Seconds: 200, Completed: true},
+		{URL: "b", Title: "B", Album: "Two", Start: at(7), Seconds: 100, Completed: true},
+		// Yesterday and the day before, today's yet to come
+		{URL: "b", Title: "B", Album: "Two", Start: at(2), Seconds: 100, Completed: true},
+		{URL: "c", Title: "C", Album: "One", Start: at(1), Seconds: 45},
+		// Skipped too soon to count
+		{URL: "c", Title: "C", Album: "One", Start: at(0), Seconds: 5},
+	}
+	s := Summarize(plays, now, 2)
+
+	if s.Plays != 6 || s.Completed != 4 || s.Skipped != 2 || s.Seconds != 650 {
+		t.Errorf("totals = %d plays, %d completed, %d skipped, %vs; want 6, 4, 2, 650s", s.Plays, s.Completed, s.Skipped, s.Seconds)
+	}
+	if s.Streak != 2 || s.LongestStreak != 3 {
+		t.Errorf("streaks = %d, %d; want 2, 3", s.Streak, s.LongestStreak)
+	}
+	// a and b both have two plays; a was listened to longer
+	if len(s.TopTracks) != 2 || s.TopTracks[0].URL != "a" || s.TopTracks[1].URL != "b" {
+		t.Errorf("TopTracks = %+v, want a then b", s.TopTracks)
+	}
+	if len(s.TopAlbums) != 2 || s.TopAlbums[0].Title != "One" || s.TopAlbums[0].Plays != 3 {
+		t.Errorf("TopAlbums = %+v, want One with 3 plays first", s.TopAlbums)
+	}
+	if len(s.Recent) != 2 || s.Recent[0].Seconds != 5 || s.Recent[1].URL != "c" {
+		t.Errorf("Recent = %+v, want the last two plays, newest first", s.Recent)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	s := Summarize(nil, time.Now(), 5)
+	if s.Plays != 0 || s.Streak != 0 || s.TopTracks == nil || s.Recent == nil {
+		t.Errorf("Summarize(nil) = %+v, want zero totals and empty lists", s)
+	}
+}
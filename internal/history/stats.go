@@ -0,0 +1,128 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// MinSeconds is how long a skipped track has to have played to count as a
+// play in the most-played lists and streaks.
+const MinSeconds = 30
+
+// Count is how often a track or album was played.
+type Count struct {
+	Title   string  `json:"title"`
+	Album   string  `json:"album,omitempty"`
+	URL     string  `json:"url,omitempty"`
+	Plays   int     `json:"plays"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Stats sums up the history.
+type Stats struct {
+	Plays         int     `json:"plays"`
+	Completed     int     `json:"completed"`
+	Skipped       int     `json:"skipped"`
+	Seconds       float64 `json:"seconds"` // total listening time
+	Streak        int     `json:"streak"`  // days in a row up to today or yesterday
+	LongestStreak int     `json:"longest_streak"`
+	TopTracks     []Count `json:"top_tracks"`
+	TopAlbums     []Count `json:"top_albums"`
+	Recent        []Play  `json:"recent"` // newest first
+}
+
+// counts reports whether p counts as a play: it was finished, or it ran
+// for at least MinSeconds before being skipped.
+func counts(p Play) bool {
+	return p.Completed || p.Seconds >= MinSeconds
+}
+
+// Summarize works out the stats for plays as of now, keeping the top n
+// tracks and albums and the n most recent plays. Days for streaks are
+// calendar days in now's location.
+func Summarize(plays []Play, now time.Time, n int) Stats {
+	s := Stats{Plays: len(plays), TopTracks: []Count{}, TopAlbums: []Count{}, Recent: []Play{}}
+	tracks := map[string]*Count{}
+	albums := map[string]*Count{}
+	days := map[time.Time]bool{}
+	for _, p := range plays {
+		s.Seconds += p.Seconds
+		if p.Completed {
+			s.Completed++
+		} else {
+			s.Skipped++
+		}
+		if !counts(p) {
+			continue
+		}
+		t := tracks[p.URL]
+		if t == nil {
+			t = &Count{Title: p.Title, Album: p.Album, URL: p.URL}
+			tracks[p.URL] = t
+		}
+		t.Plays++
+		t.Seconds += p.Seconds
+		al := albums[p.Album]
+		if al == nil {
+			al = &Count{Title: p.Album}
+			albums[p.Album] = al
+		}
+		al.Plays++
+		al.Seconds += p.Seconds
+		days[day(p.Start, now.Location())] = true
+	}
+	s.TopTracks = top(tracks, n)
+	s.TopAlbums = top(albums, n)
+	s.Streak, s.LongestStreak = streaks(days, day(now, now.Location()))
+
+	for i := len(plays) - 1; i >= 0 && len(s.Recent) < n; i-- {
+		s.Recent = append(s.Recent, plays[i])
+	}
+	return s
+}
+
+// top returns the n most played of counts, most listened breaking ties.
+func top(counts map[string]*Count, n int) []Count {
+	list := make([]Count, 0, len(counts))
+	for _, c := range counts {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Plays != b.Plays {
+			return a.Plays > b.Plays
+		}
+		if a.Seconds != b.Seconds {
+			return a.Seconds > b.Seconds
+		}
+		return a.Title < b.Title
+	})
+	return list[:min(n, len(list))]
+}
+
+// day returns midnight of t's date in loc.
+func day(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// streaks returns the run of consecutive days with plays that reaches
+// today, or yesterday if nothing has been played yet today, and the
+// longest run overall.
+func streaks(days map[time.Time]bool, today time.Time) (current, longest int) {
+	for d := range days {
+		if days[d.AddDate(0, 0, -1)] {
+			continue // not the start of a run
+		}
+		n := 1
+		for days[d.AddDate(0, 0, n)] {
+			n++
+		}
+		longest = max(longest, n)
+		end := d.AddDate(0, 0, n-1)
+		if end.Equal(today) || end.Equal(today.AddDate(0, 0, -1)) {
+			current = n
+		}
+	}
+	return current, longest
+}
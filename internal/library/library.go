@@ -0,0 +1,138 @@
+// Package library finds audio files on disk and groups them into albums that
+// play alongside the catalog.
+package library
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/tags"
+)
+
+// Genre is given to every local album.
+const Genre = "Local"
+
+// extensions are the file types the player can decode.
+var extensions = map[string]bool{".mp3": true, ".flac": true, ".ogg": true, ".wav": true}
+
+// IsAudio reports whether name looks like a file the player can decode.
+func IsAudio(name string) bool {
+	return extensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// FileURL returns the URL the player is given for a file on disk.
+func FileURL(path string) string {
+	return "file://" + path
+}
+
+// Scan walks dirs for audio files and makes an album of each folder that
+// holds any. Tags name the tracks and album and give their lengths; a track
+// without them is named after its file, and an album after its folder.
+// Albums come back sorted by path and their tracks by number, from the
+// tags or the file name, then by file name. Unreadable folders are skipped.
+func Scan(dirs []string) []data.Album {
+	folders := map[string][]string{}
+	for _, dir := range dirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if IsAudio(d.Name()) {
+				folders[filepath.Dir(path)] = append(folders[filepath.Dir(path)], path)
+			}
+			return nil
+		})
+	}
+
+	paths := make([]string, 0, len(folders))
+	for p := range folders {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	albums := make([]data.Album, 0, len(paths))
+	taken := map[string]bool{}
+	for _, folder := range paths {
+		files := folders[folder]
+		infos := make(map[string]tags.Info, len(files))
+		name := ""
+		for _, f := range files {
+			info, _ := tags.ReadFile(f)
+			infos[f] = info
+			if name == "" {
+				name = info.Album
+			}
+		}
+		sort.Slice(files, func(i, j int) bool {
+			a, b := trackNumber(files[i], infos[files[i]]), trackNumber(files[j], infos[files[j]])
+			if a != b {
+				return a < b
+			}
+			return files[i] < files[j]
+		})
+
+		album := data.Album{Title: albumTitle(folder, name, taken), Genre: Genre}
+		for _, f := range files {
+			title := infos[f].Title
+			if title == "" {
+				title = trackTitle(f)
+			}
+			album.Tracks = append(album.Tracks, data.Track{Title: title, Duration: infos[f].Duration, URL: FileURL(f)})
+		}
+		albums = append(albums, album)
+	}
+	return albums
+}
+
+// albumTitle names an album after its tag, or its folder if untagged,
+// adding the parent folder when another album already has the name (two
+// "Disc 1"s, say).
+func albumTitle(folder, tagged string, taken map[string]bool) string {
+	title := tagged
+	if title == "" {
+		title = filepath.Base(folder)
+	}
+	if taken[title] {
+		title = filepath.Base(filepath.Dir(folder)) + " - " + title
+	}
+	taken[title] = true
+	return title
+}
+
+// trackNumber is a track's number from its tags, or else from the digits
+// its file name starts with; 0 if neither has one.
+func trackNumber(path string, info tags.Info) int {
+	if info.Track > 0 {
+		return info.Track
+	}
+	name := filepath.Base(path)
+	n, _ := strconv.Atoi(name[:len(name)-len(strings.TrimLeft(name, "0123456789"))])
+	return n
+}
+
+// trackTitle makes a title from a file name, dropping the extension and a
+// leading track number like "01 ", "01 - " or "1. ".
+func trackTitle(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	rest := strings.TrimLeft(name, "0123456789")
+	if rest != name {
+		if trimmed := strings.TrimLeft(rest, " .-_"); trimmed != "" && trimmed != rest {
+			return trimmed
+		}
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package library
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTrackTitle(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/m/01 Intro.mp3", "Intro"},
+		{"/m/02 - Second Song.flac", "Second Song"},
+		{"/m/3. Third.ogg", "Third"},
+		{"/m/04_Fourth.wav", "Fourth"},
+		{"/m/1979.mp3", "1979"},
+		{"/m/99 Luftballons.mp3", "Luftballons"},
+		{"/m/No Number.mp3", "No Number"},
+	}
+	for _, tt := range tests {
+		if got := trackTitle(tt.path); got != tt.want {
+			t.Errorf("trackTitle(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{
+		"Band/First Album/02 Two.flac",
+		"Band/First Album/01 One.mp3",
+		"Band/First Album/cover.jpg",
+		"Band/Second Album/Disc 1/01 A.ogg",
+		"Other/Disc 1/01 B.WAV",
+		"Empty/notes.txt",
+		".hidden/01 Secret.mp3",
+	} {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	albums := Scan([]string{root, filepath.Join(root, "missing")})
+	var titles []string
+	for _, a := range albums {
+		titles = append(titles, a.Title)
+		if a.Genre != Genre {
+			t.Errorf("album %q genre %q, want %q", a.Title, a.Genre, Genre)
+		}
+	}
+	want := []string{"First Album", "Disc 1", "Other - Disc 1"}
+	if len(titles) != len(want) {
+		t.Fatalf("albums %q, want %q", titles, want)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Errorf("album %d = %q, want %q", i, titles[i], want[i])
+		}
+	}
+
+	first := albums[0]
+	if len(first.Tracks) != 2 || first.Tracks[0].Title != "One" || first.Tracks[1].Title != "Two" {
+		t.Fatalf("first album tracks %+v, want One, Two", first.Tracks)
+	}
+	if want := FileURL(filepath.Join(root, "Band/First Album/01 One.mp3")); first.Tracks[0].URL != want {
+		t.Errorf("track URL %q, want %q", first.Tracks[0].URL, want)
+	}
+}
+
+// taggedWAV is a one-second silent WAV with RIFF INFO tags.
+func taggedWAV(title, album, track string) []byte {
+	chunk := func(id string, body []byte) []byte {
+		b := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+		b = append(b, body...)
+		if len(body)%2 == 1 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	format := binary.LittleEndian.AppendUint16(nil, 1) // PCM
+	format = binary.LittleEndian.AppendUint16(format, 1)
+	format = binary.LittleEndian.AppendUint32(format, 8000)
+	format = binary.LittleEndian.AppendUint32(format, 16000)
+	format = binary.LittleEndian.AppendUint16(format, 2)
+	format = binary.LittleEndian.AppendUint16(format, 16)
+	info := []byte("INFO")
+	info = append(info, chunk("INAM", []byte(title+"\x00"))...)
+	info = append(info, chunk("IPRD", []byte(album+"\x00"))...)
+	info = append(info, chunk("ITRK", []byte(track+"\x00"))...)
+
+	body := []byte("WAVE")
+	body = append(body, chunk("fmt ", format)...)
+	body = append(body, chunk("LIST", info)...)
+	body = append(body, chunk("data", make([]byte, 16000))...)
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+func TestScanTags(t *testing.T) {
+	root := t.TempDir()
+	files := map[string][]byte{
+		"a.wav":        taggedWAV("Last", "Tagged Album", "2"),
+		"b.wav":        taggedWAV("First", "Tagged Album", "1"),
+		"03 Extra.wav": nil,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(root, name), body, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	albums := Scan([]string{root})
+	if len(albums) != 1 || albums[0].Title != "Tagged Album" {
+		t.Fatalf("albums %+v, want one called Tagged Album", albums)
+	}
+	var titles []string
+	for _, tr := range albums[0].Tracks {
+		titles = append(titles, tr.Title)
+	}
+	if want := []string{"First", "Last", "Extra"}; !slices.Equal(titles, want) {
+		t.Errorf("tracks %q, want %q", titles, want)
+	}
+	if d := albums[0].Tracks[0].Duration; d != time.Second {
+		t.Errorf("tagged track duration %v, want 1s", d)
+	}
+}
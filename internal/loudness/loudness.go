@@ -0,0 +1,109 @@
+// Package loudness keeps the measured loudness of each track, so tracks only
+// have to be analyzed once.
+package loudness
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Measurement is the analysis of one track.
+type Measurement struct {
+	LUFS    float64 `json:"lufs"`    // integrated loudness
+	Peak    float64 `json:"peak"`    // sample peak, 1.0 is full scale
+	Seconds float64 `json:"seconds"` // length, used to weight album loudness
+}
+
+// Store maps track URLs to measurements and persists them as JSON.
+// A nil *Store is valid and remembers nothing.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Measurement
+}
+
+// DefaultPath returns the store's file under the user cache dir.
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "dopogoto", "loudness.json")
+}
+
+// Open loads the store at path. A missing or unreadable file starts empty.
+func Open(path string) *Store {
+	s := &Store{path: path, entries: make(map[string]Measurement)}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &s.entries)
+	}
+	return s
+}
+
+// Get returns the measurement for url.
+func (s *Store) Get(url string) (Measurement, bool) {
+	if s == nil {
+		return Measurement{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.entries[url]
+	return m, ok
+}
+
+// Put records the measurement for url and saves the store.
+func (s *Store) Put(url string, m Measurement) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[url] = m
+	return s.save()
+}
+
+// Album combines the measured tracks that share url's directory, which on
+// the CDN is the album. Loudness is the length-weighted energy mean of the
+// tracks and peak is the highest track peak. Until every track of the album
+// has been measured the result covers only those that have.
+func (s *Store) Album(url string) (Measurement, bool) {
+	if s == nil {
+		return Measurement{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := path.Dir(url)
+	var energy, secs, peak float64
+	for u, m := range s.entries {
+		if path.Dir(u) != dir {
+			continue
+		}
+		energy += m.Seconds * math.Pow(10, m.LUFS/10)
+		secs += m.Seconds
+		peak = math.Max(peak, m.Peak)
+	}
+	if secs == 0 {
+		return Measurement{}, false
+	}
+	return Measurement{LUFS: 10 * math.Log10(energy/secs), Peak: peak, Seconds: secs}, true
+}
+
+// save writes the store. Caller must hold s.mu.
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
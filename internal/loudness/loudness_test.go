@@ -0,0 +1,58 @@
+package loudness
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loudness.json")
+	s := Open(path)
+	m := Measurement{LUFS: -14.2, Peak: 0.9, Seconds: 180}
+	if err := s.Put("https://cdn/a/1.mp3", m); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := Open(path).Get("https://cdn/a/1.mp3")
+	if !ok || got != m {
+		t.Errorf("reopened store has %+v, %v; want %+v", got, ok, m)
+	}
+}
+
+func TestStoreAlbum(t *testing.T) {
+	s := Open(filepath.Join(t.TempDir(), "loudness.json"))
+	s.Put("https://cdn/a/1.mp3", Measurement{LUFS: -10, Peak: 0.5, Seconds: 100})
+	s.Put("https://cdn/a/2.mp3", Measurement{LUFS: -20, Peak: 0.8, Seconds: 100})
+	s.Put("https://cdn/b/1.mp3", Measurement{LUFS: -30, Peak: 1, Seconds: 100})
+
+	got, ok := s.Album("https://cdn/a/3.mp3")
+	if !ok {
+		t.Fatal("no album measurement")
+	}
+	// Energy mean of -10 and -20 LUFS, dominated by the louder track.
+	want := 10 * math.Log10((math.Pow(10, -1)+math.Pow(10, -2))/2)
+	if math.Abs(got.LUFS-want) > 1e-9 {
+		t.Errorf("album loudness = %.3f, want %.3f", got.LUFS, want)
+	}
+	if got.Peak != 0.8 || got.Seconds != 200 {
+		t.Errorf("album peak/seconds = %v/%v, want 0.8/200", got.Peak, got.Seconds)
+	}
+
+	if _, ok := s.Album("https://cdn/c/1.mp3"); ok {
+		t.Error("unmeasured album reported a measurement")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	if err := s.Put("u", Measurement{}); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.Get("u"); ok {
+		t.Error("nil store returned a measurement")
+	}
+	if _, ok := s.Album("u"); ok {
+		t.Error("nil store returned an album measurement")
+	}
+}
@@ -0,0 +1,55 @@
+// Package mpeg decodes MPEG audio Layer III frame headers, for finding
+// frames in a stream and working out how long it plays.
+package mpeg
+
+// MPEG-1 and MPEG-2/2.5 Layer III bitrates in kbps, indexed by header bits.
+var (
+	mpeg1Bitrates = [15]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mpeg2Bitrates = [15]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+)
+
+// Header is a decoded frame header.
+type Header struct {
+	Bitrate    int // kbps
+	SampleRate int
+	Samples    int // per frame
+	Size       int // frame length in bytes, header included
+	Side       int // side info length in bytes, which follows the header
+}
+
+// ParseHeader decodes the 4-byte frame header at the start of h. ok is false
+// if h doesn't start with a Layer III header.
+func ParseHeader(h []byte) (hdr Header, ok bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return Header{}, false
+	}
+	version := (h[1] >> 3) & 3
+	layer := (h[1] >> 1) & 3
+	brIdx := h[2] >> 4
+	srIdx := (h[2] >> 2) & 3
+	pad := int((h[2] >> 1) & 1)
+	mono := h[3]>>6 == 3
+	if version == 1 || layer != 1 || brIdx == 0 || brIdx == 15 || srIdx == 3 {
+		return Header{}, false
+	}
+	rate := [3]int{44100, 48000, 32000}[srIdx]
+	if version == 3 { // MPEG-1
+		hdr = Header{Bitrate: mpeg1Bitrates[brIdx], SampleRate: rate, Samples: 1152, Side: 32}
+		hdr.Size = 144000*hdr.Bitrate/rate + pad
+		if mono {
+			hdr.Side = 17
+		}
+		return hdr, true
+	}
+	if version == 2 { // MPEG-2
+		rate /= 2
+	} else { // MPEG-2.5
+		rate /= 4
+	}
+	hdr = Header{Bitrate: mpeg2Bitrates[brIdx], SampleRate: rate, Samples: 576, Side: 17}
+	hdr.Size = 72000*hdr.Bitrate/rate + pad
+	if mono {
+		hdr.Side = 9
+	}
+	return hdr, true
+}
@@ -0,0 +1,31 @@
+package mpeg
+
+import "testing"
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		hdr    []byte
+		want   Header
+		wantOK bool
+	}{
+		{"mpeg1 128k 44.1k", []byte{0xFF, 0xFB, 0x90, 0x00}, Header{128, 44100, 1152, 417, 32}, true},
+		{"mpeg1 128k 44.1k padded", []byte{0xFF, 0xFB, 0x92, 0x00}, Header{128, 44100, 1152, 418, 32}, true},
+		{"mpeg1 320k 48k", []byte{0xFF, 0xFB, 0xE4, 0x00}, Header{320, 48000, 1152, 960, 32}, true},
+		{"mpeg1 mono", []byte{0xFF, 0xFB, 0x90, 0xC0}, Header{128, 44100, 1152, 417, 17}, true},
+		{"mpeg2 64k 22.05k", []byte{0xFF, 0xF3, 0x80, 0x00}, Header{64, 22050, 576, 208, 17}, true},
+		{"mpeg2 mono", []byte{0xFF, 0xF3, 0x80, 0xC0}, Header{64, 22050, 576, 208, 9}, true},
+		{"no sync", []byte{0x49, 0x44, 0x33, 0x04}, Header{}, false},
+		{"layer ii", []byte{0xFF, 0xFD, 0x90, 0x00}, Header{}, false},
+		{"bad bitrate", []byte{0xFF, 0xFB, 0xF0, 0x00}, Header{}, false},
+		{"short", []byte{0xFF, 0xFB}, Header{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseHeader(tt.hdr)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseHeader(% x) = %+v, %v; want %+v, %v", tt.hdr, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
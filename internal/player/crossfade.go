@@ -0,0 +1,176 @@
+package player
+
+import (
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/effects"
+)
+
+// MaxCrossfade is the longest supported crossfade.
+const MaxCrossfade = 12 * time.Second
+
+// skipFade is how long the outgoing audio fades when the user skips while
+// crossfading is on, instead of overlapping a full-length crossfade.
+const skipFade = 150 * time.Millisecond
+
+// crossfader plays out the current chain and, over the last fadeLen samples
+// of the current track, fades it out while fading the next track in. Both
+// run through effects.Gain stages into a beep.Mixer with an equal-power
+// curve. Once the fade is over it passes the next track straight through.
+type crossfader struct {
+	track   beep.StreamSeeker // current track, used to time the fade
+	ratio   float64           // output samples per track sample
+	out     *effects.Gain     // what is playing now
+	in      *effects.Gain     // the next track
+	mixer   beep.Mixer
+	fadeLen int
+	pos     int // samples into the fade, -1 before it starts
+	onStart func()
+	closer  func() error // closes the outgoing track once it's silent
+}
+
+func newCrossfader(cur beep.Streamer, track beep.StreamSeekCloser, ratio float64, next beep.Streamer, fadeLen int, onStart func()) *crossfader {
+	return &crossfader{
+		track:   track,
+		ratio:   ratio,
+		out:     &effects.Gain{Streamer: cur},
+		in:      &effects.Gain{Streamer: next},
+		fadeLen: fadeLen,
+		pos:     -1,
+		onStart: onStart,
+		closer:  track.Close,
+	}
+}
+
+func (c *crossfader) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) {
+		if c.pos < 0 {
+			remaining := int(float64(c.track.Len()-c.track.Position()) * c.ratio)
+			if remaining > c.fadeLen {
+				// Plain playback up to the start of the fade
+				m := remaining - c.fadeLen
+				if m > len(samples)-n {
+					m = len(samples) - n
+				}
+				got, ok := c.out.Streamer.Stream(samples[n : n+m])
+				n += got
+				if ok && got > 0 {
+					continue
+				}
+				remaining = 0 // current track ended early
+			}
+			c.start(remaining)
+		}
+
+		if c.pos >= c.fadeLen {
+			got, ok := c.in.Streamer.Stream(samples[n:])
+			n += got
+			return n, ok || n > 0
+		}
+
+		m := c.fadeLen - c.pos
+		if m > len(samples)-n {
+			m = len(samples) - n
+		}
+		if m > 512 {
+			m = 512 // keep gain steps small
+		}
+		t := (float64(c.pos) + float64(m)/2) / float64(c.fadeLen)
+		c.out.Gain = math.Cos(t*math.Pi/2) - 1
+		c.in.Gain = math.Sin(t*math.Pi/2) - 1
+		c.mixer.Stream(samples[n : n+m])
+		c.pos += m
+		n += m
+		if c.pos >= c.fadeLen {
+			c.mixer.Clear()
+			c.close()
+		}
+	}
+	return n, true
+}
+
+// start begins the fade over the remaining samples of the current track.
+func (c *crossfader) start(remaining int) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining < c.fadeLen {
+		c.fadeLen = remaining
+	}
+	c.pos = 0
+	c.mixer.Add(c.out, c.in)
+	if c.onStart != nil {
+		c.onStart()
+	}
+	if c.fadeLen == 0 {
+		c.close()
+	}
+}
+
+// finished reports whether the fade is over and only the next track remains.
+func (c *crossfader) finished() bool {
+	return c.pos >= 0 && c.pos >= c.fadeLen
+}
+
+func (c *crossfader) close() {
+	if c.closer != nil {
+		c.closer()
+		c.closer = nil
+	}
+}
+
+func (c *crossfader) Err() error {
+	return c.in.Streamer.Err()
+}
+
+// SetCrossfade sets how long consecutive tracks overlap (0 for gapless),
+// clamped to MaxCrossfade.
+func (p *Player) SetCrossfade(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if d > MaxCrossfade {
+		d = MaxCrossfade
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.crossfade = d
+	// Re-splice so an already queued track picks up the new setting
+	if p.spliced {
+		p.unsplice()
+		p.splice()
+	}
+}
+
+// fadeTail renders the next few milliseconds of the chain with a fade-out
+// applied, so a skip can end the old track cleanly after it's torn down.
+// Caller must hold p.mu and the output lock.
+func (p *Player) fadeTail() beep.Streamer {
+	tail := make([][2]float64, p.sampleRate.N(skipFade))
+	n, _ := p.volume.Stream(tail)
+	tail = tail[:n]
+	for i := range tail {
+		g := 1 - float64(i)/float64(len(tail))
+		tail[i][0] *= g
+		tail[i][1] *= g
+	}
+	return &pcm{samples: tail}
+}
+
+// pcm streams a fixed slice of samples.
+type pcm struct {
+	samples [][2]float64
+}
+
+func (s *pcm) Stream(samples [][2]float64) (int, bool) {
+	if len(s.samples) == 0 {
+		return 0, false
+	}
+	n := copy(samples, s.samples)
+	s.samples = s.samples[n:]
+	return n, true
+}
+
+func (s *pcm) Err() error { return nil }
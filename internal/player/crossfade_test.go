@@ -0,0 +1,86 @@
+package player
+
+import (
+	"math"
+	"testing"
+)
+
+// constTrack is n samples of a constant level.
+type constTrack struct {
+	level  float64
+	n, pos int
+	closed bool
+}
+
+func (c *constTrack) Stream(samples [][2]float64) (int, bool) {
+	if c.pos >= c.n {
+		return 0, false
+	}
+	m := min(len(samples), c.n-c.pos)
+	for i := range samples[:m] {
+		samples[i] = [2]float64{c.level, c.level}
+	}
+	c.pos += m
+	return m, true
+}
+
+func (c *constTrack) Err() error       { return nil }
+func (c *constTrack) Len() int         { return c.n }
+func (c *constTrack) Position() int    { return c.pos }
+func (c *constTrack) Seek(p int) error { c.pos = p; return nil }
+func (c *constTrack) Close() error     { c.closed = true; return nil }
+
+func TestCrossfader(t *testing.T) {
+	tests := []struct {
+		name     string
+		curLen   int
+		seek     int
+		fadeLen  int
+		wantFade int
+	}{
+		{"full fade", 10000, 0, 2000, 2000},
+		{"track shorter than fade", 1000, 0, 2000, 1000},
+		{"seek into fade window", 10000, 9500, 2000, 500},
+		{"no room left", 10000, 10000, 2000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur := &constTrack{level: 1, n: tt.curLen, pos: tt.seek}
+			next := &constTrack{level: 0.5, n: 5000}
+			started := 0
+			cf := newCrossfader(cur, cur, 1, next, tt.fadeLen, func() { started++ })
+
+			var out [][2]float64
+			buf := make([][2]float64, 700)
+			for {
+				n, ok := cf.Stream(buf)
+				out = append(out, buf[:n]...)
+				if !ok {
+					break
+				}
+			}
+
+			want := tt.curLen - tt.seek + next.n - tt.wantFade
+			if len(out) != want {
+				t.Errorf("streamed %d samples, want %d", len(out), want)
+			}
+			if started != 1 {
+				t.Errorf("onStart called %d times, want 1", started)
+			}
+			if !cur.closed {
+				t.Error("outgoing track not closed after the fade")
+			}
+			if got := out[len(out)-1][0]; got != 0.5 {
+				t.Errorf("last sample %v, want next track at full level", got)
+			}
+			// Equal-power: the midpoint of the fade is louder than either
+			// track's share alone but never clips.
+			if tt.wantFade > 0 {
+				mid := out[tt.curLen-tt.seek-tt.wantFade+tt.wantFade/2][0]
+				if mid <= 0.5 || mid > math.Sqrt2 {
+					t.Errorf("fade midpoint %v out of range", mid)
+				}
+			}
+		})
+	}
+}
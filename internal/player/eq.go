@@ -0,0 +1,101 @@
+package player
+
+import (
+	"github.com/gopxl/beep/v2"
+
+	"github.com/dangerous-person/dopogoto/internal/dsp"
+)
+
+// EQBands are the equalizer's center frequencies in Hz, an octave apart.
+var EQBands = []float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+// MaxEQGain is the most a band can boost or cut, in dB.
+const MaxEQGain = 12
+
+// eqQ gives each band roughly an octave of width.
+const eqQ = 1.41
+
+// EQPreset is a named set of band gains in dB.
+type EQPreset struct {
+	Name  string
+	Gains []float64
+}
+
+// EQPresets are the built-in equalizer settings; the first is flat.
+var EQPresets = []EQPreset{
+	{"Flat", []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	{"Bass Boost", []float64{6, 6, 5, 3, 1, 0, 0, 0, 0, 0}},
+	// Keeps the sub bass off the walls and takes the edge off the top.
+	{"Late Night", []float64{-6, -4, -2, 0, 0, 1, 1, 0, -2, -3}},
+	// Narrow, mid-heavy band like an old tape or radio.
+	{"Lo-Fi", []float64{-12, -9, -4, 1, 3, 3, 1, -4, -9, -12}},
+}
+
+// eqStage runs audio through one peaking filter per band.
+type eqStage struct {
+	beep.Streamer
+	filters [][2]dsp.Biquad // per band, per channel
+	flat    bool
+}
+
+func newEQStage(s beep.Streamer, gains []float64, rate beep.SampleRate) *eqStage {
+	e := &eqStage{Streamer: s, filters: make([][2]dsp.Biquad, len(EQBands))}
+	e.set(gains, rate)
+	return e
+}
+
+// set retunes the bands. Call with the output locked.
+func (e *eqStage) set(gains []float64, rate beep.SampleRate) {
+	e.flat = true
+	for i, freq := range EQBands {
+		var g float64
+		if i < len(gains) {
+			g = gains[i]
+		}
+		if g != 0 {
+			e.flat = false
+		}
+		// Bands too close to Nyquist for this rate are left flat.
+		if freq > 0.45*float64(rate) {
+			g = 0
+		}
+		f := dsp.Peaking(float64(rate), freq, eqQ, g)
+		e.filters[i][0].Retune(f)
+		e.filters[i][1].Retune(f)
+	}
+}
+
+func (e *eqStage) Stream(samples [][2]float64) (int, bool) {
+	n, ok := e.Streamer.Stream(samples)
+	if e.flat {
+		return n, ok
+	}
+	for i := range samples[:n] {
+		for c := range samples[i] {
+			x := samples[i][c]
+			for b := range e.filters {
+				x = e.filters[b][c].Process(x)
+			}
+			samples[i][c] = x
+		}
+	}
+	return n, ok
+}
+
+// SetEQ sets the equalizer's band gains in dB, in EQBands order. Missing
+// bands are flat and gains are clamped to ±MaxEQGain.
+func (p *Player) SetEQ(gains []float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.eqGains = make([]float64, len(EQBands))
+	for i := range p.eqGains {
+		if i < len(gains) {
+			p.eqGains[i] = max(-MaxEQGain, min(MaxEQGain, gains[i]))
+		}
+	}
+	if p.eq != nil {
+		p.out.Lock()
+		p.eq.set(p.eqGains, p.sampleRate)
+		p.out.Unlock()
+	}
+}
@@ -0,0 +1,71 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// sineStreamer is an endless stereo sine at freq Hz, full scale.
+func sineStreamer(freq float64, rate beep.SampleRate) beep.Streamer {
+	i := 0
+	return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for j := range samples {
+			v := math.Sin(2 * math.Pi * freq * float64(i) / float64(rate))
+			samples[j] = [2]float64{v, v}
+			i++
+		}
+		return len(samples), true
+	})
+}
+
+// peakDB streams a second from s and returns the peak of its second half.
+func peakDB(s beep.Streamer) float64 {
+	buf := make([][2]float64, 44100)
+	s.Stream(buf)
+	var peak float64
+	for _, v := range buf[len(buf)/2:] {
+		peak = math.Max(peak, math.Abs(v[0]))
+	}
+	return 20 * math.Log10(peak)
+}
+
+func TestEQStage(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset string
+		freq   float64
+		want   float64 // dB
+	}{
+		{"flat", "Flat", 1000, 0},
+		{"bass boost lows", "Bass Boost", 40, 6},
+		{"bass boost leaves mids", "Bass Boost", 2000, 0},
+		{"lo-fi cuts highs", "Lo-Fi", 16000, -12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gains []float64
+			for _, p := range EQPresets {
+				if p.Name == tt.preset {
+					gains = p.Gains
+				}
+			}
+			e := newEQStage(sineStreamer(tt.freq, 44100), gains, 44100)
+			if got := peakDB(e); math.Abs(got-tt.want) > 1.5 {
+				t.Errorf("%s at %v Hz: %.1f dB, want about %.0f", tt.preset, tt.freq, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetEQClamps(t *testing.T) {
+	p := NewWithOutput(NewNullOutput(1))
+	p.SetEQ([]float64{20, -20, 3})
+	want := []float64{MaxEQGain, -MaxEQGain, 3, 0, 0, 0, 0, 0, 0, 0}
+	for i, g := range p.eqGains {
+		if g != want[i] {
+			t.Fatalf("eqGains = %v, want %v", p.eqGains, want)
+		}
+	}
+}
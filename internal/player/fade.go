@@ -0,0 +1,94 @@
+package player
+
+import "time"
+
+// FadedOutMsg is sent when a FadeOut has finished and playback is paused.
+type FadedOutMsg struct{}
+
+// fadeDepth is how far FadeOut turns the volume down, in effects.Volume
+// steps (base 2): about -60 dB, inaudible.
+const fadeDepth = 10
+
+// fadeStep is how often a fade moves the volume.
+const fadeStep = 20 * time.Millisecond
+
+// FadeOut turns the volume down smoothly over d, then pauses and puts the
+// volume back for when playback resumes. Volume changes during the fade are
+// kept relative to it.
+func (p *Player) FadeOut(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cancelFade()
+	stop := make(chan struct{})
+	p.stopFade = stop
+	go p.runFade(time.Now(), d, stop)
+}
+
+// CancelFade stops a FadeOut and restores the volume.
+func (p *Player) CancelFade() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cancelFade()
+	p.fadeLevel = 0
+	p.applyVolume()
+}
+
+// cancelFade stops the fade goroutine. Caller must hold p.mu.
+func (p *Player) cancelFade() {
+	if p.stopFade != nil {
+		close(p.stopFade)
+		p.stopFade = nil
+	}
+}
+
+func (p *Player) runFade(start time.Time, d time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(fadeStep)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			progress := 1.0
+			if d > 0 {
+				progress = float64(now.Sub(start)) / float64(d)
+			}
+
+			p.mu.Lock()
+			if progress < 1 {
+				// Linear in volume steps, so evenly spaced in dB
+				p.fadeLevel = -fadeDepth * progress
+				p.applyVolume()
+				p.mu.Unlock()
+				continue
+			}
+			p.stopFade = nil
+			if p.ctrl != nil {
+				p.out.Lock()
+				p.ctrl.Paused = true
+				p.out.Unlock()
+			}
+			p.playing = false
+			p.fadeLevel = 0
+			p.applyVolume()
+			send := p.sendMsg
+			p.mu.Unlock()
+
+			if send != nil {
+				send(FadedOutMsg{})
+			}
+			return
+		}
+	}
+}
+
+// applyVolume sets the chain's volume from the user's level and any fade.
+// Caller must hold p.mu.
+func (p *Player) applyVolume() {
+	if p.volume == nil {
+		return
+	}
+	p.out.Lock()
+	p.volume.Volume = p.vol + p.fadeLevel
+	p.out.Unlock()
+}
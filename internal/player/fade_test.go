@@ -0,0 +1,59 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+func volumeOf(p *Player) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.out.Lock()
+	defer p.out.Unlock()
+	return p.volume.Volume
+}
+
+func TestFadeOut(t *testing.T) {
+	srv, _, _ := newCDN(t, silentMP3(400)) // ~10s
+	out := NewNullOutput(1)
+	t.Cleanup(out.Close)
+	p := NewWithOutput(out)
+	t.Cleanup(p.Close)
+	msgs := collect(p)
+
+	p.PlayURL(srv.URL+"/a.mp3", "A")
+	waitFor[TrackStartedMsg](t, msgs)
+	full := volumeOf(p)
+
+	p.FadeOut(400 * time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	if v := volumeOf(p); v > full-2 || v < full-fadeDepth {
+		t.Errorf("volume %.2f halfway through the fade, want between %.2f and %.2f", v, full-fadeDepth, full-2)
+	}
+	// Turning it up mid-fade moves the whole fade up.
+	p.VolumeUp()
+	if v := volumeOf(p); v > full+0.5-2 {
+		t.Errorf("volume %.2f after turning up mid-fade, want still faded", v)
+	}
+
+	waitFor[FadedOutMsg](t, msgs)
+	if p.IsPlaying() {
+		t.Error("still playing after the fade")
+	}
+	if v := volumeOf(p); v != full+0.5 {
+		t.Errorf("volume %.2f after the fade, want it restored to %.2f", v, full+0.5)
+	}
+
+	// A cancelled fade restores the volume and never pauses.
+	p.TogglePause()
+	p.FadeOut(300 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	p.CancelFade()
+	if v := volumeOf(p); v != full+0.5 {
+		t.Errorf("volume %.2f after cancelling, want %.2f", v, full+0.5)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if !p.IsPlaying() {
+		t.Error("cancelled fade paused playback")
+	}
+}
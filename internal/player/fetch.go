@@ -0,0 +1,268 @@
+package player
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/cache"
+	"github.com/dangerous-person/dopogoto/internal/urlpath"
+)
+
+// stallTimeout aborts a download that has received no data for this long.
+const stallTimeout = 30 * time.Second
+
+// MaxAttempts is how many times a track's download is tried before giving
+// up on it.
+const MaxAttempts = 5
+
+// retryBackoff is the wait before the first retry; it doubles after each
+// further failure.
+var retryBackoff = time.Second
+
+// statusError is an HTTP response other than the one asked for.
+type statusError struct {
+	code int
+	url  string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %d for %s", e.code, e.url)
+}
+
+// retryable reports whether a failed download is worth trying again:
+// network errors, stalls and server errors are, missing files aren't.
+func retryable(err error) bool {
+	var se *statusError
+	switch {
+	case errors.As(err, &se):
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	case errors.Is(err, errTooLarge):
+		return false
+	}
+	return true
+}
+
+// fetch returns a buffer holding rawURL. A cached copy is revalidated with
+// its ETag/Last-Modified and used as-is on 304 or when the network is down;
+// otherwise the track downloads into the buffer in the background and is
+// cached once complete. Transient failures are retried with exponential
+// backoff, resuming a broken download where it stopped; onRetry, if set, is
+// called with the attempt number before each retry and with 0 once a retry
+// has reconnected.
+func (p *Player) fetch(ctx context.Context, rawURL string, onRetry func(attempt int)) (*streamBuffer, error) {
+	p.mu.Lock()
+	c := p.cache
+	p.mu.Unlock()
+
+	d := &download{ctx: ctx, url: rawURL, cache: c, onRetry: onRetry, attempt: 1}
+	resp, wd, err := d.open(0)
+	for err != nil {
+		// Offline with a cached copy: play that rather than wait.
+		if _, cached := c.Lookup(rawURL); cached && ctx.Err() == nil {
+			if buf, cerr := cachedBuffer(c.Read(rawURL)); cerr == nil {
+				return buf, nil
+			}
+		}
+		if !d.retry(err) {
+			return nil, err
+		}
+		resp, wd, err = d.open(0)
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		wd.stop()
+		buf, err := cachedBuffer(c.Read(rawURL))
+		if err != nil {
+			return nil, fmt.Errorf("cache: %w", err)
+		}
+		return buf, nil
+	}
+	if resp.ContentLength > maxMP3Size {
+		resp.Body.Close()
+		wd.stop()
+		return nil, fmt.Errorf("file too large (>50 MB): %s", rawURL)
+	}
+	d.etag = resp.Header.Get("ETag")
+	d.lastModified = resp.Header.Get("Last-Modified")
+
+	buf := newStreamBuffer(resp.ContentLength)
+	go d.run(buf, resp, wd)
+	return buf, nil
+}
+
+// download is one track's transfer across however many requests it takes.
+type download struct {
+	ctx          context.Context
+	url          string
+	cache        *cache.Cache
+	onRetry      func(attempt int)
+	attempt      int
+	etag         string
+	lastModified string
+}
+
+// watchdog cancels a request that has stalled.
+type watchdog struct {
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (w watchdog) stop() {
+	w.timer.Stop()
+	w.cancel()
+}
+
+// open requests the track from byte off on. A fresh request revalidates any
+// cached copy; a resumed one asks for the rest with a Range header, sent
+// only if the track has an ETag or Last-Modified date to hold it to. A 200
+// answer to a resume is the whole track: it changed, or there was nothing
+// to hold it to. The returned watchdog must be stopped once the body is
+// done with.
+func (d *download) open(off int64) (*http.Response, watchdog, error) {
+	// Give up if the connection stalls, but let slow, steady downloads
+	// run as long as they need — playback starts long before they finish.
+	reqCtx, cancel := context.WithCancel(d.ctx)
+	wd := watchdog{time.AfterFunc(stallTimeout, cancel), cancel}
+	stop := wd.stop
+
+	// Encode URL (CDN paths may contain spaces)
+	req, err := http.NewRequestWithContext(reqCtx, "GET", urlpath.Encode(d.url), nil)
+	if err != nil {
+		stop()
+		return nil, watchdog{}, fmt.Errorf("request: %w", err)
+	}
+	validator := d.etag
+	if validator == "" {
+		validator = d.lastModified
+	}
+	want := http.StatusOK
+	if off > 0 && validator != "" {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", off))
+		req.Header.Set("If-Range", validator)
+		want = http.StatusPartialContent
+	} else if off > 0 {
+		off = 0 // nothing to tell a changed track by: start again
+	} else if entry, cached := d.cache.Lookup(d.url); cached {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		stop()
+		return nil, watchdog{}, fmt.Errorf("download: %w", err)
+	}
+	switch {
+	case resp.StatusCode == want:
+	case off == 0 && resp.StatusCode == http.StatusNotModified:
+	case off > 0 && resp.StatusCode == http.StatusOK:
+	default:
+		resp.Body.Close()
+		stop()
+		return nil, watchdog{}, &statusError{resp.StatusCode, d.url}
+	}
+	if resp.StatusCode == http.StatusPartialContent && !strings.HasPrefix(resp.Header.Get("Content-Range"), "bytes "+strconv.FormatInt(off, 10)+"-") {
+		resp.Body.Close()
+		stop()
+		return nil, watchdog{}, fmt.Errorf("download: bad Content-Range %q", resp.Header.Get("Content-Range"))
+	}
+	return resp, wd, nil
+}
+
+// retry waits out the backoff before another attempt after err. It returns
+// false when err isn't worth retrying, the attempts are used up or the
+// download was cancelled.
+func (d *download) retry(err error) bool {
+	if d.ctx.Err() != nil || !retryable(err) || d.attempt >= MaxAttempts {
+		return false
+	}
+	wait := retryBackoff << (d.attempt - 1)
+	d.attempt++
+	if d.onRetry != nil {
+		d.onRetry(d.attempt)
+	}
+	select {
+	case <-time.After(wait):
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+// run copies the body into buf, reconnecting from where it broke off until
+// the track is complete or the retries run out, then caches it.
+func (d *download) run(buf *streamBuffer, resp *http.Response, wd watchdog) {
+	retried := false
+	for {
+		err := buf.append(stallReader{resp.Body, wd.timer})
+		resp.Body.Close()
+		wd.stop()
+		if err == nil && buf.size > 0 && buf.received() < buf.size {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			buf.finish(nil)
+			d.cache.Put(d.url, buf.bytes(), d.etag, d.lastModified)
+			return
+		}
+
+		for {
+			if !d.retry(err) {
+				buf.finish(err)
+				return
+			}
+			retried = true
+			resp, wd, err = d.open(buf.received())
+			if err == nil {
+				break
+			}
+		}
+		if resp.StatusCode == http.StatusOK {
+			// The whole track again, maybe a new version: start over
+			d.etag = resp.Header.Get("ETag")
+			d.lastModified = resp.Header.Get("Last-Modified")
+			buf.restart(resp.ContentLength)
+		}
+		if retried && d.onRetry != nil {
+			d.onRetry(0)
+		}
+	}
+}
+
+// cachedBuffer wraps bytes read from the cache in an already complete buffer.
+func cachedBuffer(data []byte, err error) (*streamBuffer, error) {
+	if err != nil {
+		return nil, err
+	}
+	buf := newStreamBuffer(int64(len(data)))
+	if err := buf.fill(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// stallReader resets a watchdog timer every time data arrives.
+type stallReader struct {
+	io.Reader
+	timer *time.Timer
+}
+
+func (r stallReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	if n > 0 {
+		r.timer.Reset(stallTimeout)
+	}
+	return n, err
+}
@@ -0,0 +1,292 @@
+package player
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/cache"
+)
+
+// newCDN serves track over HTTP with an ETag, like cdn.dopogoto.com.
+func newCDN(t *testing.T, track []byte) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	var full, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Write(track)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &full, &notModified
+}
+
+func TestFetchCachesAndRevalidates(t *testing.T) {
+	track := silentMP3(50)
+	srv, full, notModified := newCDN(t, track)
+
+	c, err := cache.New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New()
+	p.SetCache(c)
+	url := srv.URL + "/Dopo Goto - Album/01 Track.mp3"
+
+	buf, err := p.fetch(context.Background(), url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.bytes(), track) {
+		t.Fatal("first fetch returned wrong bytes")
+	}
+
+	// The cache write happens right after the download finishes.
+	for i := 0; i < 100; i++ {
+		if _, ok := c.Lookup(url); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := c.Lookup(url); !ok {
+		t.Fatal("track not cached after download")
+	}
+
+	buf, err = p.fetch(context.Background(), url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.bytes(), track) {
+		t.Error("cached fetch returned wrong bytes")
+	}
+	if full.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("server saw %d full and %d conditional requests, want 1 and 1", full.Load(), notModified.Load())
+	}
+}
+
+func TestFetchFallsBackToCacheOffline(t *testing.T) {
+	track := silentMP3(10)
+	srv, _, _ := newCDN(t, track)
+	url := srv.URL + "/offline.mp3"
+
+	c, err := cache.New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put(url, track, `"abc"`, "")
+	srv.Close()
+
+	p := New()
+	p.SetCache(c)
+	buf, err := p.fetch(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("fetch with server down: %v", err)
+	}
+	if !bytes.Equal(buf.bytes(), track) {
+		t.Error("offline fetch returned wrong bytes")
+	}
+}
+
+func TestFetchHTTPError(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	if _, err := New().fetch(context.Background(), srv.URL+"/nope.mp3", nil); err == nil {
+		t.Error("fetch of 404 should fail")
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("404 requested %d times, want no retries", n)
+	}
+}
+
+// fastRetries shrinks the backoff for the length of a test.
+func fastRetries(t *testing.T) {
+	old := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = old })
+}
+
+func TestFetchRetries(t *testing.T) {
+	fastRetries(t)
+	track := silentMP3(50)
+
+	tests := []struct {
+		name     string
+		failures int32 // requests answered 503 before the track is served
+		wantErr  bool
+	}{
+		{"recovers", 2, false},
+		{"gives up", MaxAttempts, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if hits.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write(track)
+			}))
+			defer srv.Close()
+
+			var attempts []int
+			buf, err := New().fetch(context.Background(), srv.URL+"/a.mp3", func(n int) {
+				attempts = append(attempts, n)
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("fetch succeeded, want it to give up")
+				}
+				if n := hits.Load(); n != MaxAttempts {
+					t.Errorf("%d requests, want %d", n, MaxAttempts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buf.wait(); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.bytes(), track) {
+				t.Error("retried fetch returned wrong bytes")
+			}
+			if want := []int{2, 3}; len(attempts) != len(want) || attempts[0] != 2 || attempts[1] != 3 {
+				t.Errorf("retries reported %v, want %v", attempts, want)
+			}
+		})
+	}
+}
+
+func TestFetchResumesWithRange(t *testing.T) {
+	fastRetries(t)
+	track := silentMP3(200)
+	half := len(track) / 2
+
+	var ranges []string
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		if hits.Add(1) == 1 {
+			// Drop the connection halfway through the body.
+			w.Header().Set("Content-Length", strconv.Itoa(len(track)))
+			w.Write(track[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "a.mp3", time.Time{}, bytes.NewReader(track))
+	}))
+	defer srv.Close()
+
+	var attempts []int
+	buf, err := New().fetch(context.Background(), srv.URL+"/a.mp3", func(n int) {
+		attempts = append(attempts, n)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.bytes(), track) {
+		t.Error("resumed download doesn't match the track")
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes="+strconv.Itoa(half)+"-" {
+		t.Errorf("resumed with Range %q, want bytes=%d-", ranges, half)
+	}
+	if len(attempts) != 2 || attempts[0] != 2 || attempts[1] != 0 {
+		t.Errorf("retries reported %v, want [2 0]", attempts)
+	}
+}
+
+func TestFetchRestartsWhenTrackChanges(t *testing.T) {
+	fastRetries(t)
+	old, track := silentMP3(200), silent48kMP3(150)
+	half := len(old) / 2
+
+	var ifRange []string
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			// The old version, dropped halfway through
+			w.Header().Set("ETag", `"old"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(old)))
+			w.Write(old[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		ifRange = append(ifRange, r.Header.Get("If-Range"))
+		// ServeContent ignores the Range as If-Range no longer matches
+		w.Header().Set("ETag", `"new"`)
+		http.ServeContent(w, r, "a.mp3", time.Time{}, bytes.NewReader(track))
+	}))
+	defer srv.Close()
+
+	buf, err := New().fetch(context.Background(), srv.URL+"/a.mp3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.bytes(), track) {
+		t.Errorf("got %d bytes, want the new version's %d unspliced", len(buf.bytes()), len(track))
+	}
+	if len(ifRange) != 1 || ifRange[0] != `"old"` {
+		t.Errorf("resumed with If-Range %q, want the old ETag", ifRange)
+	}
+}
+
+func TestFetchRestartsWithoutValidator(t *testing.T) {
+	fastRetries(t)
+	track := silentMP3(200)
+	half := len(track) / 2
+
+	var ranges []string
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(track)))
+		if hits.Add(1) == 1 {
+			w.Write(track[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Write(track)
+	}))
+	defer srv.Close()
+
+	buf, err := New().fetch(context.Background(), srv.URL+"/a.mp3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.bytes(), track) {
+		t.Error("restarted download doesn't match the track")
+	}
+	if len(ranges) != 1 || ranges[0] != "" {
+		t.Errorf("retried with Range %q, want a fresh request", ranges)
+	}
+}
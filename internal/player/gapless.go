@@ -0,0 +1,209 @@
+package player
+
+import (
+	"context"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// queuedTrack is a fully downloaded and decoded track waiting to play.
+type queuedTrack struct {
+	url      string
+	title    string
+	streamer beep.StreamSeekCloser
+	format   beep.Format
+	source   beep.Streamer // streamer resampled and gained for output, set by splice
+	loop     *abLoop
+	gain     *gainStage
+}
+
+// SetNext tells the player which track follows the current one, so it can be
+// prefetched and spliced onto the end of the current track without a gap.
+// An empty URL clears it (e.g. at the end of the catalog).
+func (p *Player) SetNext(rawURL, title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if rawURL == p.nextURL {
+		return
+	}
+	p.clearNext()
+	p.nextURL = rawURL
+	if rawURL == "" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancelPrefetch = cancel
+	go p.prefetch(ctx, p.buf, rawURL, title)
+}
+
+// prefetch downloads and decodes the next track, then splices it in.
+func (p *Player) prefetch(ctx context.Context, cur *streamBuffer, rawURL, title string) {
+	// Let the current track finish downloading first so the two don't
+	// compete for bandwidth.
+	if cur != nil {
+		cur.wait()
+	}
+
+	// Errors are dropped here: PlayURL retries (and reports) if the
+	// prefetch never lands.
+	var data []byte
+	if _, local := localPath(rawURL); !local {
+		buf, err := p.fetch(ctx, rawURL, nil)
+		if err != nil {
+			return
+		}
+		if err := buf.wait(); err != nil {
+			return
+		}
+		data = buf.bytes()
+	}
+	streamer, format, err := openTrack(rawURL, data)
+	if err != nil {
+		return
+	}
+	// Measure before splicing so the track starts at the right level.
+	if ctx.Err() == nil && p.needsMeasuring(rawURL) {
+		p.measure(rawURL, data)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ctx.Err() != nil || p.nextURL != rawURL {
+		streamer.Close()
+		return
+	}
+	p.next = &queuedTrack{url: rawURL, title: title, streamer: streamer, format: format}
+	p.splice()
+}
+
+// splice queues the prefetched track behind the current one. Without a
+// crossfade it uses beep.Seq so the speaker crosses over on the exact sample
+// the current track ends; with one, a crossfader overlaps the two. Either way
+// a callback lets pollPosition promote the next track to current.
+// Caller must hold p.mu.
+func (p *Player) splice() {
+	if p.next == nil || p.spliced || p.ctrl == nil || p.streamer == nil || p.advanced == nil {
+		return
+	}
+	advanced := p.advanced
+	signal := func() {
+		select {
+		case advanced <- struct{}{}:
+		default:
+		}
+	}
+	p.out.Lock()
+	base := p.ctrl.Streamer
+	// A finished crossfade only passes the current track through; drop it
+	// so wrappers don't pile up track after track.
+	if cf, ok := base.(*crossfader); ok && cf.finished() {
+		base = cf.in.Streamer
+	}
+	p.spliceBase = base
+	p.next.loop = &abLoop{StreamSeekCloser: p.next.streamer}
+	p.next.gain = p.newTrackGain(p.resample(p.next.loop, p.next.format), p.next.url)
+	p.next.source = p.next.gain
+	if p.crossfade > 0 {
+		fadeLen := p.sampleRate.N(p.crossfade)
+		ratio := float64(p.sampleRate) / float64(p.format.SampleRate)
+		p.ctrl.Streamer = newCrossfader(base, p.streamer, ratio, p.next.source, fadeLen, signal)
+	} else {
+		p.ctrl.Streamer = beep.Seq(base, beep.Callback(signal), p.next.source)
+	}
+	p.out.Unlock()
+	p.spliced = true
+}
+
+// unsplice takes the queued track back out of the chain. Caller must hold p.mu.
+func (p *Player) unsplice() {
+	if p.spliced && p.ctrl != nil {
+		p.out.Lock()
+		p.ctrl.Streamer = p.spliceBase
+		p.out.Unlock()
+	}
+	p.spliced = false
+	p.spliceBase = nil
+}
+
+// advance promotes the spliced track to current once the speaker has crossed
+// into it, and announces it with a gapless TrackStartedMsg.
+func (p *Player) advance() {
+	p.mu.Lock()
+	nt := p.next
+	if !p.spliced || nt == nil {
+		p.mu.Unlock()
+		return
+	}
+	old := p.streamer
+
+	// The Seq has already moved past the old track; point ctrl straight at
+	// the new one so Seq wrappers don't pile up track after track. A
+	// crossfade is still mixing the old track out, so it stays in place and
+	// closes the old track itself once it's silent.
+	p.out.Lock()
+	_, fading := p.ctrl.Streamer.(*crossfader)
+	if !fading {
+		p.ctrl.Streamer = nt.source
+	}
+	p.out.Unlock()
+
+	p.streamer = nt.streamer
+	p.format = nt.format
+	p.loop = nt.loop
+	p.gain = nt.gain
+	p.buf = nil
+	p.next = nil
+	p.nextURL = ""
+	p.spliced = false
+	p.spliceBase = nil
+	if p.cancelPrefetch != nil {
+		p.cancelPrefetch()
+		p.cancelPrefetch = nil
+	}
+	if !fading {
+		old.Close()
+	}
+	duration := nt.format.SampleRate.D(nt.streamer.Len())
+	outputRate := p.sampleRate
+	p.mu.Unlock()
+
+	if p.sendMsg != nil {
+		p.sendMsg(TrackStartedMsg{
+			TrackTitle: nt.title,
+			Duration:   duration,
+			Gapless:    true,
+			SourceRate: int(nt.format.SampleRate),
+			OutputRate: int(outputRate),
+		})
+	}
+}
+
+// takeNext hands over the prefetched track if it is rawURL, rewound to the
+// start. Caller must hold p.mu.
+func (p *Player) takeNext(rawURL string) *queuedTrack {
+	nt := p.next
+	if nt == nil || nt.url != rawURL {
+		return nil
+	}
+	p.unsplice()
+	p.next = nil
+	nt.streamer.Seek(0)
+	return nt
+}
+
+// clearNext cancels any prefetch and drops the queued track, unsplicing it
+// first if the speaker could reach it. Caller must hold p.mu.
+func (p *Player) clearNext() {
+	if p.cancelPrefetch != nil {
+		p.cancelPrefetch()
+		p.cancelPrefetch = nil
+	}
+	p.unsplice()
+	if p.next != nil {
+		p.next.streamer.Close()
+		p.next = nil
+	}
+	p.nextURL = ""
+}
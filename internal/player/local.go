@@ -0,0 +1,78 @@
+package player
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/flac"
+	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/vorbis"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// localPath returns the path of a file:// URL, or ok=false for a remote one.
+func localPath(rawURL string) (path string, ok bool) {
+	return strings.CutPrefix(rawURL, "file://")
+}
+
+// openLocal opens a track on disk. Local files are decoded straight from
+// the file, so they can seek anywhere without being buffered first.
+func openLocal(path string) (beep.StreamSeekCloser, beep.Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	return decode(path, f)
+}
+
+// decode picks the decoder for name's extension: FLAC, Ogg Vorbis, WAV, or
+// MP3 for anything else. The decoder closes rc.
+func decode(name string, rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".flac":
+		return flac.Decode(rc)
+	case ".ogg", ".oga":
+		return vorbis.Decode(rc)
+	case ".wav":
+		return wav.Decode(rc)
+	}
+	return mp3.Decode(rc)
+}
+
+// openTrack decodes a track: a local file straight from disk, otherwise the
+// downloaded MP3 in data.
+func openTrack(rawURL string, data []byte) (beep.StreamSeekCloser, beep.Format, error) {
+	if path, ok := localPath(rawURL); ok {
+		return openLocal(path)
+	}
+	return mp3.Decode(readSeekCloser{bytes.NewReader(data)})
+}
+
+// playLocal plays a track from disk. There's nothing to buffer, so it
+// starts right away.
+func (p *Player) playLocal(ctx context.Context, rawURL, path, title string) {
+	streamer, format, err := openLocal(path)
+	if err != nil {
+		p.sendError(ctx, fmt.Errorf("open %s: %w", filepath.Base(path), err))
+		return
+	}
+	p.mu.Lock()
+	p.buf = nil
+	p.mu.Unlock()
+	if ctx.Err() != nil {
+		streamer.Close()
+		return
+	}
+	if !p.startPlayback(ctx, rawURL, streamer, format, title) {
+		return
+	}
+	if p.needsMeasuring(rawURL) {
+		p.measure(rawURL, nil)
+	}
+}
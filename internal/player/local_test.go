@@ -0,0 +1,54 @@
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// writeWAV writes d of a constant tone at rate to a WAV file in a temp dir.
+func writeWAV(t *testing.T, name string, rate beep.SampleRate, d time.Duration) string {
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	format := beep.Format{SampleRate: rate, NumChannels: 2, Precision: 2}
+	if err := wav.Encode(f, &constTrack{level: 0.25, n: rate.N(d)}, format); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlayLocalFile(t *testing.T) {
+	path := writeWAV(t, "01 Tone.WAV", 48000, 2*time.Second)
+
+	out := NewNullOutput(1)
+	t.Cleanup(out.Close)
+	p := NewWithOutput(out)
+	t.Cleanup(p.Close)
+	msgs := collect(p)
+
+	p.PlayURL("file://"+path, "Tone")
+	started := waitFor[TrackStartedMsg](t, msgs)
+	if started.Duration != 2*time.Second || started.SourceRate != 48000 {
+		t.Errorf("started %+v, want 2s at 48 kHz", started)
+	}
+
+	// Local files seek anywhere straight away.
+	p.Seek(1500 * time.Millisecond)
+	if pos := p.Position(); pos < 1500*time.Millisecond {
+		t.Errorf("position %v after seeking to 1.5s", pos)
+	}
+	waitFor[TrackEndMsg](t, msgs)
+
+	p.PlayURL("file://"+filepath.Join(filepath.Dir(path), "missing.flac"), "Missing")
+	if msg := waitFor[ErrorMsg](t, msgs); msg.Err == nil {
+		t.Error("missing file played")
+	}
+}
@@ -0,0 +1,96 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+// rampTrack's samples hold their own index, so jumps are easy to spot.
+type rampTrack struct {
+	constTrack
+}
+
+func (r *rampTrack) Stream(samples [][2]float64) (int, bool) {
+	n := min(len(samples), r.n-r.pos)
+	for i := range samples[:n] {
+		v := float64(r.pos + i)
+		samples[i] = [2]float64{v, v}
+	}
+	r.pos += n
+	return n, n > 0
+}
+
+func TestABLoop(t *testing.T) {
+	const a, b = 1000, 3000
+	l := &abLoop{StreamSeekCloser: &rampTrack{constTrack{n: 100000}}, a: a, b: b}
+
+	out := make([][2]float64, 10000)
+	for i := 0; i < len(out); i += 512 {
+		l.Stream(out[i:min(i+512, len(out))])
+	}
+
+	// Straight through to B, a crossfaded seam, then on from A.
+	period := b - a
+	for i, s := range out {
+		var want float64
+		switch {
+		case i < b:
+			want = float64(i)
+		case (i-b)%period < loopSeam:
+			continue // seam
+		default:
+			want = float64(a + (i-b)%period)
+		}
+		if s[0] != want {
+			t.Fatalf("sample %d = %v, want %v", i, s[0], want)
+		}
+	}
+	for i := b; i < b+loopSeam; i++ {
+		if s := out[i][0]; s < a || s > b+loopSeam {
+			t.Fatalf("seam sample %d = %v, want between %d and %d", i, s, a, b+loopSeam)
+		}
+	}
+
+	// A play head seeked past B plays on.
+	l.Seek(b + 100)
+	l.Stream(out[:1000])
+	if out[999][0] != b+1099 {
+		t.Errorf("after seeking past B got %v, want %v", out[999][0], b+1099)
+	}
+
+	// No loop: a plain pass-through.
+	l.a, l.b = 0, 0
+	l.Seek(0)
+	l.Stream(out)
+	if out[len(out)-1][0] != float64(len(out)-1) {
+		t.Errorf("without a loop sample %d = %v", len(out)-1, out[len(out)-1][0])
+	}
+}
+
+func TestPlayerLoopClearsOnTrackChange(t *testing.T) {
+	srv, _, _ := newCDN(t, silentMP3(400)) // ~10s
+	out := NewNullOutput(4)
+	t.Cleanup(out.Close)
+	p := NewWithOutput(out)
+	t.Cleanup(p.Close)
+	msgs := collect(p)
+
+	p.PlayURL(srv.URL+"/a.mp3", "A")
+	waitFor[TrackStartedMsg](t, msgs)
+	p.SetLoop(0, 500*time.Millisecond)
+	for out.Played() < 2*time.Second {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pos := p.Position(); pos > 600*time.Millisecond {
+		t.Errorf("position %v, want held inside the 0.5s loop", pos)
+	}
+
+	p.PlayURL(srv.URL+"/b.mp3", "B")
+	waitFor[TrackStartedMsg](t, msgs)
+	p.mu.Lock()
+	looping := p.loop.looping()
+	p.mu.Unlock()
+	if looping {
+		t.Error("loop carried over to the next track")
+	}
+}
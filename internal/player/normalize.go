@@ -0,0 +1,184 @@
+package player
+
+import (
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+
+	"github.com/dangerous-person/dopogoto/internal/dsp"
+	"github.com/dangerous-person/dopogoto/internal/loudness"
+)
+
+// Normalization selects how loudness is levelled between tracks. An album's
+// level is taken from the tracks measured so far, so in album mode it
+// settles as more of the album is played.
+type Normalization string
+
+const (
+	NormalizeOff   Normalization = "off"
+	NormalizeTrack Normalization = "track" // every track at the same loudness
+	NormalizeAlbum Normalization = "album" // albums at the same loudness, keeping their internal dynamics
+)
+
+// referenceLUFS is the loudness tracks are normalized to (as in ReplayGain 2).
+const referenceLUFS = -18.0
+
+// gainGlide is how long a gain change takes when the mode changes or a
+// measurement lands while a track is playing.
+const gainGlide = time.Second
+
+// gainStage applies a track's normalization gain.
+type gainStage struct {
+	beep.Streamer
+	url    string
+	gain   float64 // linear
+	target float64
+	step   float64 // per-sample change while gliding to target
+	rate   beep.SampleRate
+	held   bool // started unmeasured, so it plays on at unity till it ends
+}
+
+func newGainStage(s beep.Streamer, url string, gain float64, rate beep.SampleRate) *gainStage {
+	return &gainStage{Streamer: s, url: url, gain: gain, target: gain, rate: rate}
+}
+
+// newTrackGain returns the gain stage for url at its current gain. A track
+// that hasn't been measured yet starts held at unity rather than jumping
+// when its measurement lands mid-track. Caller must hold p.mu.
+func (p *Player) newTrackGain(s beep.Streamer, url string) *gainStage {
+	gain, ok := p.gainFor(url)
+	g := newGainStage(s, url, gain, p.sampleRate)
+	g.held = !ok && p.normalize != NormalizeOff
+	return g
+}
+
+func (g *gainStage) Stream(samples [][2]float64) (int, bool) {
+	n, ok := g.Streamer.Stream(samples)
+	for i := range samples[:n] {
+		if g.gain != g.target {
+			if math.Abs(g.target-g.gain) <= g.step {
+				g.gain = g.target
+			} else if g.target > g.gain {
+				g.gain += g.step
+			} else {
+				g.gain -= g.step
+			}
+		}
+		samples[i][0] *= g.gain
+		samples[i][1] *= g.gain
+	}
+	return n, ok
+}
+
+// glideTo moves the gain to target over gainGlide. Call with the output locked.
+func (g *gainStage) glideTo(target float64) {
+	g.target = target
+	g.step = math.Abs(target-g.gain) / float64(g.rate.N(gainGlide))
+}
+
+// SetLoudnessStore sets where track measurements are cached.
+func (p *Player) SetLoudnessStore(s *loudness.Store) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loudness = s
+}
+
+// SetNormalization sets the loudness normalization mode. Unknown modes turn
+// it off.
+func (p *Player) SetNormalization(mode Normalization) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch mode {
+	case NormalizeTrack, NormalizeAlbum:
+		p.normalize = mode
+	default:
+		p.normalize = NormalizeOff
+	}
+	p.refreshGains()
+}
+
+// gainFor returns the linear gain that brings url to the reference loudness,
+// held down so the track's peak doesn't clip. ok is false, with a gain of 1,
+// when normalization is off or there's no measurement. Caller must hold p.mu.
+func (p *Player) gainFor(url string) (gain float64, ok bool) {
+	var m loudness.Measurement
+	switch p.normalize {
+	case NormalizeTrack:
+		m, ok = p.loudness.Get(url)
+	case NormalizeAlbum:
+		m, ok = p.loudness.Album(url)
+	}
+	if !ok {
+		return 1, false
+	}
+	gain = math.Pow(10, (referenceLUFS-m.LUFS)/20)
+	if m.Peak > 0 && gain*m.Peak > 1 {
+		gain = 1 / m.Peak
+	}
+	return gain, true
+}
+
+// refreshGains retargets the current and queued tracks after the mode or a
+// measurement changed, leaving held ones at unity. Caller must hold p.mu.
+func (p *Player) refreshGains() {
+	if p.ctrl == nil {
+		return
+	}
+	p.out.Lock()
+	defer p.out.Unlock()
+	for _, g := range []*gainStage{p.gain, p.nextGain()} {
+		if g != nil && !g.held {
+			gain, _ := p.gainFor(g.url)
+			g.glideTo(gain)
+		}
+	}
+}
+
+// nextGain returns the queued track's gain stage, if it has one. Caller
+// must hold p.mu.
+func (p *Player) nextGain() *gainStage {
+	if p.next == nil {
+		return nil
+	}
+	return p.next.gain
+}
+
+// needsMeasuring reports whether url should be analyzed.
+func (p *Player) needsMeasuring(url string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.normalize == NormalizeOff || p.loudness == nil {
+		return false
+	}
+	_, ok := p.loudness.Get(url)
+	return !ok
+}
+
+// measure decodes a track (see openTrack), measures its loudness and stores
+// the result. It takes a while, so callers run it off the UI and audio paths.
+func (p *Player) measure(url string, data []byte) {
+	s, format, err := openTrack(url, data)
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	meter := dsp.NewMeter(int(format.SampleRate))
+	buf := make([][2]float64, 4096)
+	for {
+		n, ok := s.Stream(buf)
+		meter.Write(buf[:n])
+		if !ok {
+			break
+		}
+	}
+	lufs, ok := meter.Loudness()
+	if !ok {
+		return // silence: leave the gain alone
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loudness.Put(url, loudness.Measurement{LUFS: lufs, Peak: meter.Peak(), Seconds: meter.Seconds()})
+	p.refreshGains()
+}
@@ -0,0 +1,88 @@
+package player
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/gopxl/beep/v2"
+
+	"github.com/dangerous-person/dopogoto/internal/loudness"
+)
+
+func TestGainFor(t *testing.T) {
+	store := loudness.Open(filepath.Join(t.TempDir(), "loudness.json"))
+	store.Put("https://cdn/dnb/1.mp3", loudness.Measurement{LUFS: -8, Peak: 1, Seconds: 100})
+	store.Put("https://cdn/amb/1.mp3", loudness.Measurement{LUFS: -28, Peak: 0.5, Seconds: 100})
+	store.Put("https://cdn/amb/2.mp3", loudness.Measurement{LUFS: -24, Peak: 0.2, Seconds: 100})
+
+	db := func(g float64) float64 { return math.Pow(10, g/20) }
+	tests := []struct {
+		name string
+		mode Normalization
+		url  string
+		want float64
+	}{
+		{"off", NormalizeOff, "https://cdn/dnb/1.mp3", 1},
+		{"loud track turned down", NormalizeTrack, "https://cdn/dnb/1.mp3", db(-10)},
+		{"quiet track held below clipping", NormalizeTrack, "https://cdn/amb/1.mp3", 2},
+		{"unmeasured track", NormalizeTrack, "https://cdn/amb/3.mp3", 1},
+		{"album", NormalizeAlbum, "https://cdn/amb/2.mp3", 2},
+		{"album of one", NormalizeAlbum, "https://cdn/dnb/1.mp3", db(-10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewWithOutput(NewNullOutput(1))
+			p.SetLoudnessStore(store)
+			p.SetNormalization(tt.mode)
+			p.mu.Lock()
+			got, _ := p.gainFor(tt.url)
+			p.mu.Unlock()
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("gainFor(%s) = %.4f, want %.4f", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmeasuredTrackHeld(t *testing.T) {
+	store := loudness.Open(filepath.Join(t.TempDir(), "loudness.json"))
+	p := NewWithOutput(NewNullOutput(1))
+	p.SetLoudnessStore(store)
+	p.SetNormalization(NormalizeTrack)
+	p.mu.Lock()
+	p.gain = p.newTrackGain(&constTrack{level: 1, n: 44100}, "https://cdn/dnb/1.mp3")
+	p.ctrl = &beep.Ctrl{Streamer: p.gain}
+	p.mu.Unlock()
+
+	// The measurement lands mid-track: the track plays on unchanged
+	store.Put("https://cdn/dnb/1.mp3", loudness.Measurement{LUFS: -8, Peak: 1, Seconds: 100})
+	p.mu.Lock()
+	p.refreshGains()
+	p.mu.Unlock()
+	if p.gain.target != 1 {
+		t.Errorf("gain retargeted to %.4f mid-track, want it held at 1", p.gain.target)
+	}
+
+	p.mu.Lock()
+	next := p.newTrackGain(&constTrack{level: 1, n: 44100}, "https://cdn/dnb/1.mp3")
+	p.mu.Unlock()
+	if want := math.Pow(10, -10.0/20); next.held || math.Abs(next.gain-want) > 1e-9 {
+		t.Errorf("next play starts at %.4f (held %v), want %.4f", next.gain, next.held, want)
+	}
+}
+
+func TestGainStageGlides(t *testing.T) {
+	g := newGainStage(&constTrack{level: 1, n: 44100 * 2}, "u", 1, 44100)
+	g.glideTo(0.5)
+	buf := make([][2]float64, 22050)
+	g.Stream(buf)
+	if v := buf[len(buf)-1][0]; v <= 0.5 || v >= 1 {
+		t.Errorf("halfway through the glide level is %v, want between 0.5 and 1", v)
+	}
+	g.Stream(buf)
+	g.Stream(buf)
+	if v := buf[len(buf)-1][0]; v != 0.5 {
+		t.Errorf("after the glide level is %v, want 0.5", v)
+	}
+}
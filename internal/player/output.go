@@ -0,0 +1,205 @@
+package player
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// Output is where the player sends audio. It mirrors the speaker package so
+// the player can be rerouted to a file or run headless.
+type Output interface {
+	Init(sampleRate beep.SampleRate, bufferSize int) error
+	Play(s ...beep.Streamer)
+	Clear()
+	// Lock and Unlock guard streamers that are playing from the audio thread.
+	Lock()
+	Unlock()
+}
+
+// speakerOutput plays through the sound card.
+type speakerOutput struct{}
+
+func (speakerOutput) Init(sampleRate beep.SampleRate, bufferSize int) error {
+	return speaker.Init(sampleRate, bufferSize)
+}
+
+func (speakerOutput) Play(s ...beep.Streamer) { speaker.Play(s...) }
+func (speakerOutput) Clear()                  { speaker.Clear() }
+func (speakerOutput) Lock()                   { speaker.Lock() }
+func (speakerOutput) Unlock()                 { speaker.Unlock() }
+
+// sink stands in for the sound card: a mixer pulled by a goroutine at a
+// given multiple of real time. It backs NullOutput and WAVOutput.
+type sink struct {
+	mu     sync.Mutex
+	mixer  beep.Mixer
+	speed  float64 // 1 is real time, <= 0 is as fast as possible
+	rate   beep.SampleRate
+	start  time.Time
+	played int // samples pulled since Init
+	stop   chan struct{}
+}
+
+func (s *sink) init(sampleRate beep.SampleRate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rate = sampleRate
+	s.start = time.Now()
+	s.played = 0
+	s.stop = make(chan struct{})
+}
+
+// pull mixes the next len(samples) samples, then sleeps until the wall clock
+// has caught up with them. Like a sound card it never plays faster to make up
+// for a slow producer; the lag is dropped instead.
+func (s *sink) pull(samples [][2]float64) {
+	s.mu.Lock()
+	idle := s.mixer.Len() == 0
+	s.mixer.Stream(samples)
+	s.played += len(samples)
+	var wait time.Duration
+	if s.speed > 0 {
+		due := s.start.Add(time.Duration(float64(s.rate.D(s.played)) / s.speed))
+		wait = time.Until(due)
+		if wait < 0 {
+			s.start = s.start.Add(-wait)
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case wait > 0:
+		time.Sleep(wait)
+	case s.speed <= 0 && idle:
+		// Nothing playing: don't spin.
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *sink) Play(st ...beep.Streamer) {
+	s.mu.Lock()
+	s.mixer.Add(st...)
+	s.mu.Unlock()
+}
+
+func (s *sink) Clear() {
+	s.mu.Lock()
+	s.mixer.Clear()
+	s.mu.Unlock()
+}
+
+func (s *sink) Lock()   { s.mu.Lock() }
+func (s *sink) Unlock() { s.mu.Unlock() }
+
+// Played returns how much audio the sink has consumed.
+func (s *sink) Played() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rate == 0 {
+		return 0
+	}
+	return s.rate.D(s.played)
+}
+
+// NullOutput discards audio while still advancing playback, in real time or
+// faster. Useful for tests and headless runs.
+type NullOutput struct {
+	sink
+	done chan struct{}
+}
+
+// NewNullOutput returns a NullOutput running at speed times real time; a
+// speed of 0 or less pulls audio as fast as possible.
+func NewNullOutput(speed float64) *NullOutput {
+	return &NullOutput{sink: sink{speed: speed}}
+}
+
+func (o *NullOutput) Init(sampleRate beep.SampleRate, bufferSize int) error {
+	o.Close()
+	o.init(sampleRate)
+	o.done = make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		buf := make([][2]float64, bufferSize)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				o.pull(buf)
+			}
+		}
+	}(o.stop, o.done)
+	return nil
+}
+
+// Close stops pulling audio.
+func (o *NullOutput) Close() {
+	if o.done == nil {
+		return
+	}
+	close(o.stop)
+	<-o.done
+	o.done = nil
+}
+
+// WAVOutput records everything played to a 16-bit stereo WAV file, in real
+// time or faster.
+type WAVOutput struct {
+	sink
+	f    *os.File
+	done chan error
+}
+
+// NewWAVOutput creates path and returns an output that writes to it at speed
+// times real time (0 or less for as fast as possible).
+func NewWAVOutput(path string, speed float64) (*WAVOutput, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &WAVOutput{sink: sink{speed: speed}, f: f}, nil
+}
+
+// Init starts recording. The sample rate is fixed by the first call; later
+// calls are ignored since a WAV file can only have one rate.
+func (o *WAVOutput) Init(sampleRate beep.SampleRate, bufferSize int) error {
+	if o.done != nil {
+		return nil
+	}
+	o.init(sampleRate)
+	o.done = make(chan error, 1)
+	stop := o.stop
+	format := beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2}
+	go func() {
+		o.done <- wav.Encode(o.f, beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+			select {
+			case <-stop:
+				return 0, false
+			default:
+			}
+			o.pull(samples)
+			return len(samples), true
+		}), format)
+	}()
+	return nil
+}
+
+// Close finishes the WAV header and closes the file.
+func (o *WAVOutput) Close() error {
+	var err error
+	if o.done != nil {
+		close(o.stop)
+		err = <-o.done
+		o.done = nil
+	}
+	if cerr := o.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
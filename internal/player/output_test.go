@@ -0,0 +1,149 @@
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// collect routes the player's messages to a channel.
+func collect(p *Player) <-chan interface{} {
+	ch := make(chan interface{}, 256)
+	p.SetSendFunc(func(msg interface{}) { ch <- msg })
+	return ch
+}
+
+// waitFor returns the next message of type T, skipping others.
+func waitFor[T any](t *testing.T, ch <-chan interface{}) T {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-ch:
+			if m, ok := msg.(T); ok {
+				return m
+			}
+			if m, ok := msg.(ErrorMsg); ok {
+				t.Fatalf("player error: %v", m.Err)
+			}
+		case <-timeout:
+			var zero T
+			t.Fatalf("timed out waiting for %T", zero)
+			return zero
+		}
+	}
+}
+
+func TestPlayerHeadless(t *testing.T) {
+	srv, _, _ := newCDN(t, silentMP3(80)) // ~2.1s
+	out := NewNullOutput(2)
+	t.Cleanup(out.Close)
+	p := NewWithOutput(out)
+	t.Cleanup(p.Close)
+	msgs := collect(p)
+
+	p.PlayURL(srv.URL+"/a.mp3", "A")
+	started := waitFor[TrackStartedMsg](t, msgs)
+	if started.TrackTitle != "A" {
+		t.Errorf("started %q, want A", started.TrackTitle)
+	}
+	if want := 80 * 1152 * time.Second / 44100; started.Duration != want {
+		t.Errorf("duration %v, want %v", started.Duration, want)
+	}
+
+	p.Seek(1500 * time.Millisecond)
+	if pos := p.Position(); pos < 1500*time.Millisecond {
+		t.Errorf("position after seek %v, want >= 1.5s", pos)
+	}
+
+	// Skip: the new track starts from the top.
+	p.PlayURL(srv.URL+"/b.mp3", "B")
+	started = waitFor[TrackStartedMsg](t, msgs)
+	if started.TrackTitle != "B" {
+		t.Errorf("started %q, want B", started.TrackTitle)
+	}
+	if pos := p.Position(); pos > time.Second {
+		t.Errorf("position after skip %v, want near 0", pos)
+	}
+
+	waitFor[TrackEndMsg](t, msgs)
+	if p.IsPlaying() {
+		t.Error("still playing after TrackEndMsg")
+	}
+	if played := out.Played(); played < 2*time.Second {
+		t.Errorf("output consumed %v, want at least a track's worth", played)
+	}
+}
+
+func TestPlayerResamples(t *testing.T) {
+	srvA, _, _ := newCDN(t, silentMP3(400))
+	srvB, _, _ := newCDN(t, silent48kMP3(40))
+	out := NewNullOutput(4)
+	t.Cleanup(out.Close)
+	p := NewWithOutput(out)
+	t.Cleanup(p.Close)
+	msgs := collect(p)
+
+	p.PlayURL(srvA.URL+"/a.mp3", "A")
+	waitFor[TrackStartedMsg](t, msgs)
+	p.PlayURL(srvB.URL+"/b.mp3", "B")
+	started := waitFor[TrackStartedMsg](t, msgs)
+	if started.SourceRate != 48000 || started.OutputRate != 44100 {
+		t.Errorf("rates %d -> %d, want 48000 -> 44100", started.SourceRate, started.OutputRate)
+	}
+
+	// One second at 48 kHz must come out as one second at 44.1 kHz.
+	p.mu.Lock()
+	s := p.resample(&constTrack{level: 1, n: 48000}, beep.Format{SampleRate: 48000})
+	p.mu.Unlock()
+	n := 0
+	buf := make([][2]float64, 512)
+	for {
+		got, ok := s.Stream(buf)
+		n += got
+		if !ok {
+			break
+		}
+	}
+	if n < 44100-10 || n > 44100+10 {
+		t.Errorf("resampled 48000 samples to %d, want ~44100", n)
+	}
+}
+
+func TestWAVOutput(t *testing.T) {
+	srv, _, _ := newCDN(t, silentMP3(40))
+	path := filepath.Join(t.TempDir(), "out.wav")
+	out, err := NewWAVOutput(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewWithOutput(out)
+	msgs := collect(p)
+
+	p.PlayURL(srv.URL+"/a.mp3", "A")
+	waitFor[TrackEndMsg](t, msgs)
+	p.Close()
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, format, err := wav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.SampleRate != 44100 || format.NumChannels != 2 {
+		t.Errorf("format %+v, want 44.1kHz stereo", format)
+	}
+	if s.Len() < 40*1152 {
+		t.Errorf("recorded %d samples, want at least %d", s.Len(), 40*1152)
+	}
+}
@@ -4,17 +4,15 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/effects"
 	"github.com/gopxl/beep/v2/mp3"
-	"github.com/gopxl/beep/v2/speaker"
+
+	"github.com/dangerous-person/dopogoto/internal/cache"
+	"github.com/dangerous-person/dopogoto/internal/loudness"
 )
 
 // readSeekCloser wraps bytes.Reader to implement io.ReadSeekCloser.
@@ -35,21 +33,36 @@ type TrackEndMsg struct{}
 
 type BufferingMsg struct {
 	TrackTitle string
+	Attempt    int // download retry under way, 0 once it has reconnected
+	Attempts   int // retries allowed before giving up
 }
 
 type TrackStartedMsg struct {
 	TrackTitle string
 	Duration   time.Duration
+	Gapless    bool // spliced in from the track queued with SetNext
+	SourceRate int  // track's sample rate, Hz
+	OutputRate int  // output's sample rate, Hz; differs when resampling
 }
 
 type ErrorMsg struct {
 	Err error
 }
 
+// resampleQuality is passed to beep.Resample; 4 is transparent for music at
+// a modest CPU cost.
+const resampleQuality = 4
+
 type Player struct {
 	mu       sync.Mutex
+	out      Output
 	streamer beep.StreamSeekCloser
+	loop     *abLoop    // current track's A–B loop
+	gain     *gainStage // current track's normalization
 	ctrl     *beep.Ctrl
+	speed    *speedStage
+	eq       *eqStage
+	tap      *tap // feeds Spectrum
 	volume   *effects.Volume
 	format   beep.Format
 
@@ -59,11 +72,41 @@ type Player struct {
 	stopPoll       chan struct{}
 	cancelDownload context.CancelFunc
 	initiated      bool
+	sampleRate     beep.SampleRate // output rate
+	crossfade      time.Duration
+	cache          *cache.Cache
+	loudness       *loudness.Store
+	normalize      Normalization
+	eqGains        []float64
+	playSpeed      float64
+	fadeLevel      float64 // volume offset while fading out, <= 0
+	stopFade       chan struct{}
+	speedMode      SpeedMode
+	buf            *streamBuffer // current track's download
+
+	// Gapless: the upcoming track, prefetched and spliced in behind the
+	// current one (see gapless.go)
+	next           *queuedTrack
+	nextURL        string
+	spliced        bool
+	spliceBase     beep.Streamer // ctrl's streamer before splicing
+	advanced       chan struct{}
+	cancelPrefetch context.CancelFunc
 }
 
+// New returns a player that plays through the sound card.
 func New() *Player {
+	return NewWithOutput(speakerOutput{})
+}
+
+// NewWithOutput returns a player that sends its audio to out.
+func NewWithOutput(out Output) *Player {
 	return &Player{
-		vol: -1.0, // slightly below max
+		out:       out,
+		vol:       -1.0, // slightly below max
+		normalize: NormalizeOff,
+		playSpeed: 1,
+		speedMode: SpeedStretch,
 	}
 }
 
@@ -74,20 +117,29 @@ func (p *Player) SetSendFunc(fn func(interface{})) {
 	p.sendMsg = fn
 }
 
-// InitSpeaker initializes the speaker (call once)
+// SetCache sets the on-disk cache checked before downloading a track.
+func (p *Player) SetCache(c *cache.Cache) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cache = c
+}
+
+// InitSpeaker initializes the output (call once)
 func (p *Player) InitSpeaker(sampleRate beep.SampleRate) error {
 	if p.initiated {
 		return nil
 	}
-	err := speaker.Init(sampleRate, sampleRate.N(time.Second/30))
+	err := p.out.Init(sampleRate, sampleRate.N(time.Second/30))
 	if err != nil {
 		return fmt.Errorf("speaker init: %w", err)
 	}
 	p.initiated = true
+	p.sampleRate = sampleRate
 	return nil
 }
 
-// PlayURL downloads an MP3 from a URL and starts playing it.
+// PlayURL streams an MP3 from a URL, starting playback once a few seconds
+// are buffered while the rest downloads in the background.
 // NOTE: Caller must set buffering state before calling this (to avoid deadlock
 // when called from within bubbletea's Update).
 func (p *Player) PlayURL(rawURL, title string) {
@@ -100,54 +152,52 @@ func (p *Player) PlayURL(rawURL, title string) {
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	p.cancelDownload = cancel
+	// Reuse the prefetched track if it's the one being asked for.
+	queued := p.takeNext(rawURL)
+	p.clearNext()
 	p.mu.Unlock()
 
 	go func() {
 		defer cancel()
 
-		// Encode URL (CDN paths may contain spaces)
-		encodedURL := encodeURL(rawURL)
-
-		// Download with 2min timeout, cancellable on next track
-		dlCtx, dlCancel := context.WithTimeout(ctx, 120*time.Second)
-		defer dlCancel()
-
-		req, err := http.NewRequestWithContext(dlCtx, "GET", encodedURL, nil)
-		if err != nil {
-			p.sendError(ctx, fmt.Errorf("request: %w", err))
+		if queued != nil {
+			p.startPlayback(ctx, rawURL, queued.streamer, queued.format, queued.title)
 			return
 		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			p.sendError(ctx, fmt.Errorf("download: %w", err))
+		if path, ok := localPath(rawURL); ok {
+			p.playLocal(ctx, rawURL, path, title)
 			return
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != 200 {
-			p.sendError(ctx, fmt.Errorf("HTTP %d for %s", resp.StatusCode, rawURL))
+		buf, err := p.fetch(ctx, rawURL, func(attempt int) {
+			if ctx.Err() == nil && p.sendMsg != nil {
+				p.sendMsg(BufferingMsg{TrackTitle: title, Attempt: attempt, Attempts: MaxAttempts})
+			}
+		})
+		if err != nil {
+			p.sendError(ctx, err)
 			return
 		}
+		p.mu.Lock()
+		p.buf = buf
+		p.mu.Unlock()
 
-		const maxMP3Size = 50 << 20 // 50 MB
-		data, err := io.ReadAll(io.LimitReader(resp.Body, maxMP3Size+1))
+		// Start playing once a few seconds of audio have arrived.
+		stopProgress := p.reportProgress(ctx, buf, title)
+		err = buf.waitBuffered(startBuffer)
+		stopProgress()
 		if err != nil {
-			p.sendError(ctx, fmt.Errorf("read body: %w", err))
-			return
-		}
-		if len(data) > maxMP3Size {
-			p.sendError(ctx, fmt.Errorf("file too large (>50 MB): %s", rawURL))
+			p.sendError(ctx, fmt.Errorf("download: %w", err))
 			return
 		}
 
-		// Bail if cancelled (user skipped to another track during download)
+		// Bail if cancelled (user skipped to another track while buffering)
 		if ctx.Err() != nil {
 			return
 		}
 
 		// Decode MP3
-		streamer, format, err := mp3.Decode(readSeekCloser{bytes.NewReader(data)})
+		streamer, format, err := newLiveStreamer(buf)
 		if err != nil {
 			p.sendError(ctx, fmt.Errorf("decode mp3: %w", err))
 			return
@@ -159,52 +209,108 @@ func (p *Player) PlayURL(rawURL, title string) {
 			return
 		}
 
-		p.mu.Lock()
-
-		// Initialize speaker on first track
-		if !p.initiated {
-			if err := p.InitSpeaker(format.SampleRate); err != nil {
-				p.mu.Unlock()
-				streamer.Close()
-				p.sendError(ctx, err)
-				return
-			}
+		if !p.startPlayback(ctx, rawURL, streamer, format, title) {
+			return
 		}
 
-		p.streamer = streamer
-		p.format = format
-		// Audio chain: source -> ctrl -> volume -> speaker
-		p.ctrl = &beep.Ctrl{Streamer: streamer}
-		p.volume = &effects.Volume{
-			Streamer: p.ctrl,
-			Base:     2,
-			Volume:   p.vol,
+		// Once the whole file is in, switch to a decoder that has indexed
+		// every frame so Len() and Seek() are exact.
+		if err := buf.wait(); err != nil {
+			return
 		}
-		p.playing = true
+		full, _, err := mp3.Decode(readSeekCloser{bytes.NewReader(buf.bytes())})
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		if p.streamer != streamer {
+			p.mu.Unlock()
+			full.Close()
+			return
+		}
+		p.out.Lock()
+		streamer.attach(full)
+		p.out.Unlock()
 		p.mu.Unlock()
 
-		duration := format.SampleRate.D(streamer.Len())
+		if p.needsMeasuring(rawURL) {
+			p.measure(rawURL, buf.bytes())
+		}
+	}()
+}
+
+// startPlayback wires streamer into the audio chain and starts playing it.
+// It returns false if playback could not start.
+func (p *Player) startPlayback(ctx context.Context, rawURL string, streamer beep.StreamSeekCloser, format beep.Format, title string) bool {
+	p.mu.Lock()
 
-		if p.sendMsg != nil {
-			p.sendMsg(TrackStartedMsg{
-				TrackTitle: title,
-				Duration:   duration,
-			})
+	// Initialize speaker on first track
+	if !p.initiated {
+		if err := p.InitSpeaker(format.SampleRate); err != nil {
+			p.mu.Unlock()
+			streamer.Close()
+			p.sendError(ctx, err)
+			return false
 		}
+	}
+
+	p.streamer = streamer
+	p.format = format
+	// Audio chain: source -> loop -> resample -> gain -> ctrl -> speed -> EQ ->
+	// tap -> volume -> output
+	p.loop = &abLoop{StreamSeekCloser: streamer}
+	p.gain = p.newTrackGain(p.resample(p.loop, format), rawURL)
+	p.ctrl = &beep.Ctrl{Streamer: p.gain}
+	p.speed = newSpeedStage(p.ctrl, p.playSpeed, p.speedMode)
+	p.eq = newEQStage(p.speed, p.eqGains, p.sampleRate)
+	p.tap = &tap{Streamer: p.eq}
+	p.volume = &effects.Volume{
+		Streamer: p.tap,
+		Base:     2,
+		Volume:   p.vol + p.fadeLevel,
+	}
+	p.playing = true
+	outputRate := p.sampleRate
+	p.mu.Unlock()
 
-		// Play with callback for track end
-		done := make(chan bool)
-		speaker.Play(beep.Seq(p.volume, beep.Callback(func() {
-			done <- true
-		})))
+	duration := format.SampleRate.D(streamer.Len())
 
-		// Start position polling
-		p.mu.Lock()
-		p.stopPoll = make(chan struct{})
-		stopCh := p.stopPoll // copy ref under mutex for goroutine
-		p.mu.Unlock()
-		go p.pollPosition(done, stopCh)
-	}()
+	if p.sendMsg != nil {
+		p.sendMsg(TrackStartedMsg{
+			TrackTitle: title,
+			Duration:   duration,
+			SourceRate: int(format.SampleRate),
+			OutputRate: int(outputRate),
+		})
+	}
+
+	// Play with callback for track end. Buffered: the callback runs on the
+	// audio thread under the output lock and must never block.
+	done := make(chan bool, 1)
+	p.out.Play(beep.Seq(p.volume, beep.Callback(func() {
+		done <- true
+	})))
+
+	// Start position polling
+	p.mu.Lock()
+	p.stopPoll = make(chan struct{})
+	p.advanced = make(chan struct{}, 1)
+	stopCh := p.stopPoll // copy refs under mutex for goroutine
+	advanced := p.advanced
+	p.mu.Unlock()
+	go p.pollPosition(done, advanced, stopCh)
+	return true
+}
+
+// resample converts s from the track's rate to the output's. The output is
+// initialized once at the first track's rate, so later tracks at other rates
+// would otherwise play at the wrong pitch and speed. Positions and seeks stay
+// on the source streamer, in the track's own rate.
+func (p *Player) resample(s beep.Streamer, format beep.Format) beep.Streamer {
+	if format.SampleRate == p.sampleRate {
+		return s
+	}
+	return beep.Resample(resampleQuality, format.SampleRate, p.sampleRate, s)
 }
 
 // sendError sends an ErrorMsg only if the context hasn't been cancelled.
@@ -218,7 +324,7 @@ func (p *Player) sendError(ctx context.Context, err error) {
 	}
 }
 
-func (p *Player) pollPosition(done chan bool, stopCh <-chan struct{}) {
+func (p *Player) pollPosition(done chan bool, advanced <-chan struct{}, stopCh <-chan struct{}) {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -227,12 +333,12 @@ func (p *Player) pollPosition(done chan bool, stopCh <-chan struct{}) {
 		case <-ticker.C:
 			p.mu.Lock()
 			if p.streamer != nil && p.format.SampleRate != 0 {
-				// Hold p.mu across the speaker.Lock to prevent Stop()
+				// Hold p.mu across the output lock to prevent Stop()
 				// from closing the streamer between our unlock and lock.
-				speaker.Lock()
+				p.out.Lock()
 				pos := p.format.SampleRate.D(p.streamer.Position())
 				length := p.format.SampleRate.D(p.streamer.Len())
-				speaker.Unlock()
+				p.out.Unlock()
 				p.mu.Unlock()
 
 				if p.sendMsg != nil {
@@ -241,6 +347,8 @@ func (p *Player) pollPosition(done chan bool, stopCh <-chan struct{}) {
 			} else {
 				p.mu.Unlock()
 			}
+		case <-advanced:
+			p.advance()
 		case <-done:
 			p.mu.Lock()
 			p.playing = false
@@ -270,12 +378,34 @@ func (p *Player) Stop() {
 		p.stopPoll = nil
 	}
 
-	speaker.Clear()
+	// With crossfading on, a skip fades the old track out quickly rather
+	// than cutting it off or overlapping a full-length fade.
+	var tail beep.Streamer
+	p.out.Lock()
+	if p.crossfade > 0 && p.playing && p.volume != nil {
+		tail = p.fadeTail()
+	}
+	if p.ctrl != nil {
+		if cf, ok := p.ctrl.Streamer.(*crossfader); ok {
+			cf.close()
+		}
+	}
+	p.out.Unlock()
+	p.out.Clear()
+	if tail != nil {
+		p.out.Play(tail)
+	}
 
 	if p.streamer != nil {
 		p.streamer.Close()
 		p.streamer = nil
 	}
+	p.loop = nil
+	p.gain = nil
+	p.buf = nil
+	p.spliced = false
+	p.spliceBase = nil
+	p.advanced = nil
 	p.playing = false
 }
 
@@ -288,10 +418,10 @@ func (p *Player) TogglePause() bool {
 		return false
 	}
 
-	speaker.Lock()
+	p.out.Lock()
 	p.ctrl.Paused = !p.ctrl.Paused
 	p.playing = !p.ctrl.Paused
-	speaker.Unlock()
+	p.out.Unlock()
 
 	return p.playing
 }
@@ -312,11 +442,7 @@ func (p *Player) VolumeUp() float64 {
 	if p.vol > 0 {
 		p.vol = 0
 	}
-	if p.volume != nil {
-		speaker.Lock()
-		p.volume.Volume = p.vol
-		speaker.Unlock()
-	}
+	p.applyVolume()
 	return p.vol
 }
 
@@ -329,14 +455,19 @@ func (p *Player) VolumeDown() float64 {
 	if p.vol < -5 {
 		p.vol = -5
 	}
-	if p.volume != nil {
-		speaker.Lock()
-		p.volume.Volume = p.vol
-		speaker.Unlock()
-	}
+	p.applyVolume()
 	return p.vol
 }
 
+// SetVolumeLevel sets the volume on the same 0-10 scale VolumeLevel reports.
+func (p *Player) SetVolumeLevel(level int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.vol = float64(min(max(level, 0), 10))/2 - 5
+	p.applyVolume()
+}
+
 // Volume returns current volume level (0-10 scale for display)
 func (p *Player) VolumeLevel() int {
 	p.mu.Lock()
@@ -362,9 +493,12 @@ func (p *Player) Seek(d time.Duration) {
 		pos = p.streamer.Len() - 1
 	}
 
-	speaker.Lock()
+	p.out.Lock()
 	p.streamer.Seek(pos)
-	speaker.Unlock()
+	if p.speed != nil {
+		p.speed.reset()
+	}
+	p.out.Unlock()
 }
 
 // Position returns current playback position
@@ -376,28 +510,17 @@ func (p *Player) Position() time.Duration {
 		return 0
 	}
 
-	speaker.Lock()
+	p.out.Lock()
 	pos := p.format.SampleRate.D(p.streamer.Position())
-	speaker.Unlock()
+	p.out.Unlock()
 	return pos
 }
 
 // Close cleans up resources
 func (p *Player) Close() {
 	p.Stop()
-}
-
-// encodeURL properly encodes a URL that may contain spaces in the path
-func encodeURL(rawURL string) string {
-	u, err := url.Parse(rawURL)
-	if err != nil {
-		return rawURL
-	}
-	// Encode each path segment
-	parts := strings.Split(u.Path, "/")
-	for i, part := range parts {
-		parts[i] = url.PathEscape(part)
-	}
-	u.RawPath = strings.Join(parts, "/")
-	return u.String()
+	p.mu.Lock()
+	p.cancelFade()
+	p.clearNext()
+	p.mu.Unlock()
 }
@@ -0,0 +1,70 @@
+package player
+
+import (
+	"context"
+	"time"
+)
+
+// progressEvery is how often download progress is reported while a track
+// buffers.
+const progressEvery = 250 * time.Millisecond
+
+// stallAfter is how long a download goes without data before its rate is
+// reported as 0.
+const stallAfter = time.Second
+
+// DownloadProgressMsg reports how a track's download is going while it
+// buffers, so a stalled download can be told from a slow one.
+type DownloadProgressMsg struct {
+	TrackTitle string
+	Received   int64   // bytes downloaded so far
+	Needed     int64   // bytes needed before playback starts; -1 until known
+	Total      int64   // Content-Length, or -1 if unknown
+	Rate       float64 // bytes per second, smoothed; 0 when stalled
+}
+
+// reportProgress sends DownloadProgressMsg for buf every progressEvery
+// until the returned stop func is called. stop waits for the last message
+// to go out, so none arrives after the track has started.
+func (p *Player) reportProgress(ctx context.Context, buf *streamBuffer, title string) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(progressEvery)
+		defer ticker.Stop()
+
+		last, lastAt, lastData := buf.received(), time.Now(), time.Now()
+		var rate float64
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				got := buf.received()
+				instant := float64(got-last) / now.Sub(lastAt).Seconds()
+				if got > last {
+					lastData = now
+				}
+				last, lastAt = got, now
+				switch {
+				case now.Sub(lastData) >= stallAfter:
+					rate = 0
+				case rate == 0:
+					rate = instant
+				default:
+					rate = 0.7*rate + 0.3*instant
+				}
+				if ctx.Err() == nil && p.sendMsg != nil {
+					p.sendMsg(DownloadProgressMsg{TrackTitle: title, Received: got, Needed: buf.bytesFor(startBuffer), Total: buf.size, Rate: rate})
+				}
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-done
+	}
+}
@@ -0,0 +1,67 @@
+package player
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDownloadProgress(t *testing.T) {
+	// Trickle the track out so buffering takes a second or so.
+	track := silentMP3(400)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(track)))
+		for off := 0; off < len(track); off += 10 * 417 {
+			if _, err := w.Write(track[off:min(off+10*417, len(track))]); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	out := NewNullOutput(1)
+	t.Cleanup(out.Close)
+	p := NewWithOutput(out)
+	t.Cleanup(p.Close)
+	msgs := collect(p)
+
+	p.PlayURL(srv.URL+"/a.mp3", "A")
+	progress := waitFor[DownloadProgressMsg](t, msgs)
+	if progress.TrackTitle != "A" || progress.Total != int64(len(track)) {
+		t.Errorf("progress %+v, want title A and total %d", progress, len(track))
+	}
+	if progress.Received <= 0 || progress.Rate <= 0 {
+		t.Errorf("progress %+v, want bytes received at a non-zero rate", progress)
+	}
+
+	// The bar runs up to startBuffer's worth of frames, not the whole file,
+	// so it's most of the way full by the time the track starts.
+	startFrames := (44100*int(startBuffer/time.Millisecond)/1000 + 1151) / 1152
+	for {
+		m := <-msgs
+		if _, ok := m.(TrackStartedMsg); ok {
+			break
+		}
+		if m, ok := m.(DownloadProgressMsg); ok {
+			progress = m
+		}
+	}
+	if want := int64(startFrames * 417); progress.Needed != want {
+		t.Errorf("needed %d bytes to start, want %d", progress.Needed, want)
+	}
+	if progress.Received*2 < progress.Needed {
+		t.Errorf("last progress before the start had %d of %d bytes, want the bar over half full", progress.Received, progress.Needed)
+	}
+
+	// Progress stops once the track is playing.
+	time.Sleep(2 * progressEvery)
+	for len(msgs) > 0 {
+		if m, ok := (<-msgs).(DownloadProgressMsg); ok {
+			t.Fatalf("progress %+v after the track started", m)
+		}
+	}
+}
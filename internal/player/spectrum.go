@@ -0,0 +1,104 @@
+package player
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/gopxl/beep/v2"
+
+	"github.com/dangerous-person/dopogoto/internal/dsp"
+)
+
+// fftSize is how many recent samples the spectrum is computed over: ~46 ms
+// at 44.1 kHz, short enough to follow the beat.
+const fftSize = 2048
+
+const (
+	spectrumLow   = 40.0    // Hz, bottom of the lowest band
+	spectrumHigh  = 16000.0 // Hz, top of the highest band
+	spectrumFloor = -60.0   // dB shown as an empty band
+)
+
+// tap keeps the most recent samples that went through it, mixed to mono,
+// for the visualizer. It sits before the volume so the bars don't shrink
+// when the volume is turned down.
+type tap struct {
+	beep.Streamer
+	ring [fftSize]float64
+	pos  int
+}
+
+func (t *tap) Stream(samples [][2]float64) (int, bool) {
+	n, ok := t.Streamer.Stream(samples)
+	for _, s := range samples[:n] {
+		t.ring[t.pos] = (s[0] + s[1]) / 2
+		t.pos = (t.pos + 1) % fftSize
+	}
+	return n, ok
+}
+
+// snapshot returns the ring oldest sample first. Call with the output locked.
+func (t *tap) snapshot() []float64 {
+	out := make([]float64, fftSize)
+	n := copy(out, t.ring[t.pos:])
+	copy(out[n:], t.ring[:t.pos])
+	return out
+}
+
+// Spectrum returns the level of what's playing in n log-spaced bands from
+// 40 Hz to 16 kHz, each 0 (silent) to 1 (full scale). It returns nil when
+// nothing is playing. Meant to be polled at the UI's frame rate.
+func (p *Player) Spectrum(n int) []float64 {
+	p.mu.Lock()
+	if !p.playing || p.tap == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	p.out.Lock()
+	samples := p.tap.snapshot()
+	p.out.Unlock()
+	rate := p.sampleRate
+	p.mu.Unlock()
+
+	return bandLevels(samples, float64(rate), n)
+}
+
+// bandLevels computes the spectrum of samples and sums it into n bands.
+func bandLevels(samples []float64, rate float64, n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	size := len(samples)
+	win := dsp.Hann(size)
+	var winSum float64
+	x := make([]complex128, size)
+	for i, s := range samples {
+		x[i] = complex(s*win[i], 0)
+		winSum += win[i]
+	}
+	dsp.FFT(x)
+
+	high := math.Min(spectrumHigh, 0.45*rate)
+	binHz := rate / float64(size)
+	levels := make([]float64, n)
+	for b := range levels {
+		lo := spectrumLow * math.Pow(high/spectrumLow, float64(b)/float64(n))
+		hi := spectrumLow * math.Pow(high/spectrumLow, float64(b+1)/float64(n))
+		first := int(math.Ceil(lo / binHz))
+		last := int(math.Ceil(hi/binHz)) - 1
+		if last < first {
+			// Narrower than a bin: use the one nearest the band's center.
+			first = int(math.Round(math.Sqrt(lo*hi) / binHz))
+			last = first
+		}
+		// Amplitude of a full-scale sine comes out as 1 (0 dB).
+		var power float64
+		for k := first; k <= last; k++ {
+			a := cmplx.Abs(x[k]) * 2 / winSum
+			power += a * a
+		}
+		db := 10 * math.Log10(power+1e-12)
+		levels[b] = math.Max(0, math.Min(1, 1-db/spectrumFloor))
+	}
+	return levels
+}
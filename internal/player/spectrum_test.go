@@ -0,0 +1,61 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gopxl/beep/v2"
+)
+
+func TestTapSpectrum(t *testing.T) {
+	const bands = 24
+	tests := []struct {
+		name  string
+		freq  float64 // 0 for silence
+		level float64 // amplitude
+	}{
+		{"bass", 60, 1},
+		{"mid", 1000, 0.5},
+		{"treble", 10000, 1},
+		{"silence", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sine := sineStreamer(tt.freq, 44100)
+			tp := &tap{Streamer: beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+				n, ok := sine.Stream(samples)
+				for i := range samples[:n] {
+					samples[i][0] *= tt.level
+					samples[i][1] *= tt.level
+				}
+				return n, ok
+			})}
+			tp.Stream(make([][2]float64, 3000)) // wraps the ring
+			levels := bandLevels(tp.snapshot(), 44100, bands)
+
+			if tt.freq == 0 {
+				for b, l := range levels {
+					if l != 0 {
+						t.Errorf("band %d = %.2f in silence", b, l)
+					}
+				}
+				return
+			}
+			want := int(float64(bands) * math.Log(tt.freq/spectrumLow) / math.Log(spectrumHigh/spectrumLow))
+			loudest := 0
+			for b, l := range levels {
+				if l > levels[loudest] {
+					loudest = b
+				}
+			}
+			if loudest != want {
+				t.Errorf("loudest band = %d, want %d (%v)", loudest, want, levels)
+			}
+			// Full scale reads near the top, -6 dB a tenth lower.
+			wantLevel := 1 + 20*math.Log10(tt.level)/-spectrumFloor
+			if math.Abs(levels[loudest]-wantLevel) > 0.05 {
+				t.Errorf("level = %.3f, want %.3f", levels[loudest], wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package player
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// SpeedMode selects how the playback rate is changed.
+type SpeedMode string
+
+const (
+	SpeedStretch SpeedMode = "stretch" // tempo changes, pitch stays
+	SpeedTape    SpeedMode = "tape"    // pitch follows speed, like a tape sped up
+)
+
+// Playback rate limits, as multiples of normal speed.
+const (
+	MinSpeed = 0.5
+	MaxSpeed = 2.0
+)
+
+// speedStage plays its source faster or slower. It sits after ctrl, so
+// everything upstream, including positions and crossfades, stays in track
+// time.
+type speedStage struct {
+	src    beep.Streamer
+	speed  float64
+	mode   SpeedMode
+	active beep.Streamer // src at normal speed, else a resampler or stretcher over it
+}
+
+func newSpeedStage(src beep.Streamer, speed float64, mode SpeedMode) *speedStage {
+	s := &speedStage{src: src}
+	s.set(speed, mode)
+	return s
+}
+
+// set changes the rate, keeping the current resampler or stretcher when it
+// can so there's no gap. Call with the output locked.
+func (s *speedStage) set(speed float64, mode SpeedMode) {
+	switch {
+	case speed == 1:
+		s.active = s.src
+	case mode == SpeedTape:
+		if r, ok := s.active.(*beep.Resampler); ok {
+			r.SetRatio(speed)
+		} else {
+			s.active = beep.ResampleRatio(resampleQuality, speed, s.src)
+		}
+	default:
+		if st, ok := s.active.(*stretcher); ok {
+			st.speed = speed
+		} else {
+			s.active = newStretcher(s.src, speed)
+		}
+	}
+	s.speed, s.mode = speed, mode
+}
+
+// reset drops audio buffered from before a seek. Call with the output locked.
+func (s *speedStage) reset() {
+	s.active = nil
+	s.set(s.speed, s.mode)
+}
+
+func (s *speedStage) Stream(samples [][2]float64) (int, bool) {
+	return s.active.Stream(samples)
+}
+
+func (s *speedStage) Err() error {
+	return s.src.Err()
+}
+
+// SetSpeed sets the playback rate, clamped to MinSpeed..MaxSpeed and rounded
+// to hundredths.
+func (p *Player) SetSpeed(speed float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.playSpeed = math.Round(max(MinSpeed, min(MaxSpeed, speed))*100) / 100
+	p.applySpeed()
+}
+
+// Speed returns the playback rate.
+func (p *Player) Speed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playSpeed
+}
+
+// SetSpeedMode sets whether a changed rate keeps the pitch. Unknown modes
+// keep it.
+func (p *Player) SetSpeedMode(mode SpeedMode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if mode != SpeedTape {
+		mode = SpeedStretch
+	}
+	p.speedMode = mode
+	p.applySpeed()
+}
+
+// applySpeed passes the rate settings to the chain. Caller must hold p.mu.
+func (p *Player) applySpeed() {
+	if p.speed == nil {
+		return
+	}
+	p.out.Lock()
+	p.speed.set(p.playSpeed, p.speedMode)
+	p.out.Unlock()
+}
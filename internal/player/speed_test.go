@@ -0,0 +1,116 @@
+package player
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// pitch estimates the frequency of a sine from its rising zero crossings.
+func pitch(samples [][2]float64, rate float64) float64 {
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1][0] < 0 && samples[i][0] >= 0 {
+			crossings++
+		}
+	}
+	return float64(crossings) * rate / float64(len(samples))
+}
+
+func TestSpeedStage(t *testing.T) {
+	tests := []struct {
+		mode      SpeedMode
+		speed     float64
+		wantPitch float64
+	}{
+		{SpeedStretch, 1, 440},
+		{SpeedStretch, 0.5, 440},
+		{SpeedStretch, 1.5, 440},
+		{SpeedStretch, 2, 440},
+		{SpeedTape, 0.5, 220},
+		{SpeedTape, 2, 880},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %.1fx", tt.mode, tt.speed), func(t *testing.T) {
+			sine := sineStreamer(440, 44100)
+			consumed := 0
+			src := beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+				n, ok := sine.Stream(samples)
+				consumed += n
+				return n, ok
+			})
+			s := newSpeedStage(src, tt.speed, tt.mode)
+
+			out := make([][2]float64, 2*44100)
+			for i := 0; i < len(out); i += 512 {
+				s.Stream(out[i:min(i+512, len(out))])
+			}
+			if got := float64(consumed) / float64(len(out)); math.Abs(got-tt.speed) > 0.03*tt.speed+0.05 {
+				t.Errorf("consumed %.2fx the output, want %.2fx", got, tt.speed)
+			}
+			if got := pitch(out[44100:], 44100); math.Abs(got-tt.wantPitch) > 0.02*tt.wantPitch {
+				t.Errorf("pitch %.1f Hz, want %.1f", got, tt.wantPitch)
+			}
+			// Grains that line up don't beat: every 10 ms still peaks near 1.
+			for i := 44100; i+441 <= len(out); i += 441 {
+				var peak float64
+				for _, v := range out[i : i+441] {
+					peak = math.Max(peak, v[0])
+				}
+				if peak < 0.9 {
+					t.Errorf("level dips to %.2f at sample %d", peak, i)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestStretcherEnds(t *testing.T) {
+	src := &constTrack{level: 0.5, n: 10000}
+	s := newStretcher(src, 2)
+	n := 0
+	buf := make([][2]float64, 512)
+	for i := 0; i < 100; i++ {
+		got, ok := s.Stream(buf)
+		n += got
+		if !ok {
+			break
+		}
+	}
+	if want := 5000; n < want || n > want+stretchFrame {
+		t.Errorf("stretched 10000 samples at 2x to %d, want ~%d", n, want)
+	}
+}
+
+func TestPlayerSpeedKeepsTrackTime(t *testing.T) {
+	srv, _, _ := newCDN(t, silentMP3(80)) // ~2.1s
+	out := NewNullOutput(2)
+	t.Cleanup(out.Close)
+	p := NewWithOutput(out)
+	t.Cleanup(p.Close)
+	msgs := collect(p)
+
+	p.SetSpeed(2)
+	p.PlayURL(srv.URL+"/a.mp3", "A")
+	started := waitFor[TrackStartedMsg](t, msgs)
+	if want := 80 * 1152 * time.Second / 44100; started.Duration != want {
+		t.Errorf("duration %v, want %v", started.Duration, want)
+	}
+
+	for out.Played() < 500*time.Millisecond {
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Half a second of output is a second of the track.
+	if pos := p.Position(); pos < 900*time.Millisecond || pos > 1400*time.Millisecond {
+		t.Errorf("position %v after 0.5s at 2x, want ~1s", pos)
+	}
+
+	waitFor[TrackEndMsg](t, msgs)
+	if played := out.Played(); played > 1500*time.Millisecond {
+		t.Errorf("output consumed %v for a 2.1s track at 2x, want ~1.05s", played)
+	}
+}
@@ -0,0 +1,434 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/mp3"
+
+	"github.com/dangerous-person/dopogoto/internal/mpeg"
+)
+
+const (
+	maxMP3Size  = 50 << 20        // 50 MB
+	startBuffer = 3 * time.Second // audio buffered before playback starts
+	lowWater    = 16 << 10        // bytes kept ahead of the decoder to avoid blocking the speaker
+)
+
+// errTooLarge is returned when a download exceeds maxMP3Size.
+var errTooLarge = errors.New("file too large (>50 MB)")
+
+// frameIndex records where each complete MP3 frame starts in the download.
+type frameIndex struct {
+	starts          []int64
+	samplesPerFrame int
+	sampleRate      int
+	scan            int64 // offset of the next header to inspect
+	tagChecked      bool
+}
+
+// update indexes any frames that are complete in data.
+func (fi *frameIndex) update(data []byte) {
+	if !fi.tagChecked {
+		if len(data) < 10 {
+			return
+		}
+		fi.tagChecked = true
+		if string(data[:3]) == "ID3" {
+			size := int64(data[6]&0x7F)<<21 | int64(data[7]&0x7F)<<14 | int64(data[8]&0x7F)<<7 | int64(data[9]&0x7F)
+			fi.scan = 10 + size
+			if data[5]&0x10 != 0 { // footer present
+				fi.scan += 10
+			}
+		}
+	}
+	for fi.scan+4 <= int64(len(data)) {
+		hdr, ok := mpeg.ParseHeader(data[fi.scan : fi.scan+4])
+		if !ok {
+			fi.scan++ // resync
+			continue
+		}
+		if fi.scan+int64(hdr.Size) > int64(len(data)) {
+			return
+		}
+		if fi.samplesPerFrame == 0 {
+			fi.samplesPerFrame = hdr.Samples
+			fi.sampleRate = hdr.SampleRate
+		}
+		fi.starts = append(fi.starts, fi.scan)
+		fi.scan += int64(hdr.Size)
+	}
+}
+
+// streamBuffer holds a track that is still downloading. fill appends to it in
+// the background while readers block until the bytes they need have arrived.
+type streamBuffer struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	data   []byte
+	size   int64 // expected size from Content-Length, or -1 if unknown
+	done   bool
+	err    error
+	frames frameIndex
+}
+
+func newStreamBuffer(size int64) *streamBuffer {
+	b := &streamBuffer{size: size}
+	if size > 0 && size <= maxMP3Size {
+		b.data = make([]byte, 0, size)
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// fill copies r into the buffer until EOF or error, waking blocked readers as
+// data arrives, then marks the download finished. It returns the download
+// error, if any.
+func (b *streamBuffer) fill(r io.Reader) error {
+	err := b.append(r)
+	b.finish(err)
+	return err
+}
+
+// append copies r onto the end of the buffer until EOF (returning nil) or
+// an error, leaving the download open so a resumed request can carry on.
+func (b *streamBuffer) append(r io.Reader) error {
+	chunk := make([]byte, 32<<10)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			b.mu.Lock()
+			if len(b.data)+n > maxMP3Size {
+				b.mu.Unlock()
+				return errTooLarge
+			}
+			b.data = append(b.data, chunk[:n]...)
+			b.frames.update(b.data)
+			b.cond.Broadcast()
+			b.mu.Unlock()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// restart empties the buffer for the download to begin again with a track
+// of size bytes, or -1 if unknown.
+func (b *streamBuffer) restart(size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.size = size
+	b.data = nil
+	if size > 0 && size <= maxMP3Size {
+		b.data = make([]byte, 0, size)
+	}
+	b.frames = frameIndex{}
+}
+
+// received returns how many bytes have arrived so far.
+func (b *streamBuffer) received() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int64(len(b.data))
+}
+
+// finish marks the download complete (err == nil) or failed.
+func (b *streamBuffer) finish(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return
+	}
+	b.done = true
+	b.err = err
+	b.cond.Broadcast()
+}
+
+// wait blocks until the download has finished and returns its error.
+func (b *streamBuffer) wait() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for !b.done {
+		b.cond.Wait()
+	}
+	return b.err
+}
+
+// waitBuffered blocks until d of audio is downloaded or the download ends.
+func (b *streamBuffer) waitBuffered(d time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for !b.done {
+		fi := &b.frames
+		if fi.sampleRate > 0 {
+			buffered := beep.SampleRate(fi.sampleRate).D(len(fi.starts) * fi.samplesPerFrame)
+			if buffered >= d {
+				return nil
+			}
+		}
+		b.cond.Wait()
+	}
+	return b.err
+}
+
+// bytesFor estimates how many bytes hold the first d of audio, from the
+// frames indexed so far. It's -1 until the first frame is in.
+func (b *streamBuffer) bytesFor(d time.Duration) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fi := &b.frames
+	indexed := len(fi.starts)
+	if indexed == 0 {
+		return -1
+	}
+	frames := (beep.SampleRate(fi.sampleRate).N(d) + fi.samplesPerFrame - 1) / fi.samplesPerFrame
+	switch {
+	case frames < indexed:
+		return fi.starts[frames]
+	case frames == indexed:
+		return fi.scan
+	}
+	first := fi.starts[0]
+	n := first + (fi.scan-first)*int64(frames)/int64(indexed)
+	if b.size > 0 {
+		n = min(n, b.size)
+	}
+	return n
+}
+
+// bytes returns the downloaded data. Only call once wait has returned.
+func (b *streamBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data
+}
+
+// ahead reports whether n bytes past off are downloaded, or the download has
+// ended so no more will arrive.
+func (b *streamBuffer) ahead(off, n int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done || int64(len(b.data))-off >= n
+}
+
+// frameStart returns the byte offset of frame f if it has been downloaded.
+func (b *streamBuffer) frameStart(f int) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if f < 0 || f >= len(b.frames.starts) {
+		return 0, false
+	}
+	return b.frames.starts[f], true
+}
+
+// samplesPerFrame returns the frame length in samples, or 0 before the first
+// frame has been indexed.
+func (b *streamBuffer) samplesPerFrame() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.frames.samplesPerFrame
+}
+
+// estimatedSamples extrapolates the track length from the frames indexed so
+// far and the expected download size.
+func (b *streamBuffer) estimatedSamples() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fi := &b.frames
+	n := len(fi.starts) * fi.samplesPerFrame
+	if b.done || b.size <= 0 || len(fi.starts) == 0 {
+		return n
+	}
+	first := fi.starts[0]
+	indexed := fi.scan - first
+	if indexed <= 0 {
+		return n
+	}
+	return int(float64(n) * float64(b.size-first) / float64(indexed))
+}
+
+// reader returns a reader over the buffer starting at off.
+func (b *streamBuffer) reader(off int64) *bufferReader {
+	return &bufferReader{buf: b, pos: off}
+}
+
+// bufferReader reads sequentially from a streamBuffer, blocking until data
+// is available. It deliberately does not implement io.Seeker so go-mp3 won't
+// scan the whole (still downloading) file up front.
+type bufferReader struct {
+	buf *streamBuffer
+	pos int64
+}
+
+func (r *bufferReader) Read(p []byte) (int, error) {
+	b := r.buf
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for r.pos >= int64(len(b.data)) && !b.done {
+		b.cond.Wait()
+	}
+	if r.pos >= int64(len(b.data)) {
+		if b.err != nil {
+			return 0, b.err
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, b.data[r.pos:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+func (r *bufferReader) Close() error { return nil }
+
+// liveStreamer plays an MP3 while it is still downloading. Until attach is
+// called it decodes sequentially out of the streamBuffer: seeks inside the
+// downloaded region restart the decoder at the nearest frame, seeks past it
+// are held until the bytes arrive. Underruns play silence instead of
+// blocking the speaker.
+type liveStreamer struct {
+	buf     *streamBuffer
+	rd      *bufferReader
+	dec     beep.StreamSeekCloser
+	full    beep.StreamSeekCloser // fully indexed decoder, set by attach
+	pos     int
+	pending int // deferred seek target, -1 if none
+}
+
+func newLiveStreamer(buf *streamBuffer) (*liveStreamer, beep.Format, error) {
+	rd := buf.reader(0)
+	dec, format, err := mp3.Decode(rd)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	return &liveStreamer{buf: buf, rd: rd, dec: dec, pending: -1}, format, nil
+}
+
+func (s *liveStreamer) Stream(samples [][2]float64) (int, bool) {
+	if s.full != nil {
+		return s.full.Stream(samples)
+	}
+	if s.pending >= 0 && s.seekLive(s.pending) == nil {
+		s.pending = -1
+	}
+	if s.pending >= 0 || !s.buf.ahead(s.rd.pos, lowWater) {
+		// Still downloading: play silence rather than stall the speaker.
+		for i := range samples {
+			samples[i] = [2]float64{}
+		}
+		return len(samples), true
+	}
+	n, ok := s.dec.Stream(samples)
+	s.pos += n
+	return n, ok
+}
+
+func (s *liveStreamer) Err() error {
+	if s.full != nil {
+		return s.full.Err()
+	}
+	return s.dec.Err()
+}
+
+// Len is exact once attached; before that it is estimated from Content-Length.
+func (s *liveStreamer) Len() int {
+	if s.full != nil {
+		return s.full.Len()
+	}
+	return s.buf.estimatedSamples()
+}
+
+func (s *liveStreamer) Position() int {
+	if s.full != nil {
+		return s.full.Position()
+	}
+	if s.pending >= 0 {
+		return s.pending
+	}
+	return s.pos
+}
+
+func (s *liveStreamer) Seek(p int) error {
+	if s.full != nil {
+		return s.full.Seek(p)
+	}
+	if err := s.seekLive(p); err != nil {
+		s.pending = p
+		return nil
+	}
+	s.pending = -1
+	return nil
+}
+
+// seekLive restarts the sequential decoder at sample p. Like go-mp3's own
+// Seek it decodes from the frame before the target so the bit reservoir is
+// primed, then discards samples up to p.
+func (s *liveStreamer) seekLive(p int) error {
+	spf := s.buf.samplesPerFrame()
+	if spf == 0 {
+		return fmt.Errorf("seek %d: no frames yet", p)
+	}
+	f := p / spf
+	first := f - 1
+	if first < 0 {
+		first = 0
+	}
+	start, ok := s.buf.frameStart(first)
+	if !ok {
+		return fmt.Errorf("seek %d: not downloaded yet", p)
+	}
+	if _, ok := s.buf.frameStart(f); !ok {
+		return fmt.Errorf("seek %d: not downloaded yet", p)
+	}
+	rd := s.buf.reader(start)
+	dec, _, err := mp3.Decode(rd)
+	if err != nil {
+		return err
+	}
+	skip := make([][2]float64, 512)
+	for left := p - first*spf; left > 0; {
+		n := left
+		if n > len(skip) {
+			n = len(skip)
+		}
+		got, ok := dec.Stream(skip[:n])
+		left -= got
+		if !ok {
+			break
+		}
+	}
+	s.dec.Close()
+	s.dec = dec
+	s.rd = rd
+	s.pos = p
+	return nil
+}
+
+// attach switches playback to a decoder over the complete file, picking up at
+// the current (or pending) position. Call with the output locked.
+func (s *liveStreamer) attach(full beep.StreamSeekCloser) {
+	target := s.Position()
+	if target >= full.Len() {
+		target = full.Len() - 1
+	}
+	if target < 0 {
+		target = 0
+	}
+	full.Seek(target)
+	s.dec.Close()
+	s.full = full
+}
+
+func (s *liveStreamer) Close() error {
+	if s.full != nil {
+		s.full.Close()
+	}
+	return s.dec.Close()
+}
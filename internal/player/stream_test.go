@@ -0,0 +1,139 @@
+package player
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2/mp3"
+)
+
+// silentMP3 builds n silent MPEG-1 Layer III frames (128 kbps, 44.1 kHz),
+// each 417 bytes and 1152 samples long.
+func silentMP3(n int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < n; i++ {
+		b.Write([]byte{0xFF, 0xFB, 0x90, 0x00})
+		b.Write(make([]byte, 413))
+	}
+	return b.Bytes()
+}
+
+// silent48kMP3 is silentMP3 at 48 kHz: 384-byte frames of 1152 samples.
+func silent48kMP3(n int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < n; i++ {
+		b.Write([]byte{0xFF, 0xFB, 0x94, 0x00})
+		b.Write(make([]byte, 380))
+	}
+	return b.Bytes()
+}
+
+func TestFrameIndexSkipsID3(t *testing.T) {
+	tag := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20}
+	data := append(append(tag, make([]byte, 20)...), silentMP3(3)...)
+
+	var fi frameIndex
+	fi.update(data)
+	if len(fi.starts) != 3 {
+		t.Fatalf("indexed %d frames, want 3", len(fi.starts))
+	}
+	if fi.starts[0] != 30 {
+		t.Errorf("first frame at %d, want 30", fi.starts[0])
+	}
+}
+
+func TestLiveStreamerSeek(t *testing.T) {
+	data := silentMP3(200)
+	pr, pw := io.Pipe()
+	buf := newStreamBuffer(int64(len(data)))
+	go buf.fill(pr)
+
+	// Deliver the first half only.
+	half := 100 * 417
+	go pw.Write(data[:half])
+	if err := buf.waitBuffered(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := newLiveStreamer(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if got, want := s.Len(), 200*1152; got < want*9/10 || got > want*11/10 {
+		t.Errorf("estimated Len() = %d, want about %d", got, want)
+	}
+
+	// Wait until the whole half is indexed.
+	for {
+		if _, ok := buf.frameStart(99); ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Inside the downloaded region: immediate.
+	s.Seek(50 * 1152)
+	if s.pending != -1 || s.Position() != 50*1152 {
+		t.Errorf("seek inside region: pending=%d pos=%d", s.pending, s.Position())
+	}
+
+	// Past it: held until the data arrives.
+	target := 150*1152 + 7
+	s.Seek(target)
+	if s.pending != target {
+		t.Errorf("seek past region: pending=%d, want %d", s.pending, target)
+	}
+	if s.Position() != target {
+		t.Errorf("Position() while pending = %d, want %d", s.Position(), target)
+	}
+
+	pw.Write(data[half:])
+	pw.Close()
+	if err := buf.wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([][2]float64, 100)
+	s.Stream(samples)
+	if s.pending != -1 {
+		t.Fatalf("pending seek not applied after download finished")
+	}
+	if got := s.Position(); got != target+100 {
+		t.Errorf("Position() = %d, want %d", got, target+100)
+	}
+
+	full, _, err := mp3.Decode(readSeekCloser{bytes.NewReader(buf.bytes())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.attach(full)
+	if s.Len() != 200*1152 {
+		t.Errorf("Len() after attach = %d, want %d", s.Len(), 200*1152)
+	}
+	if s.Position() != target+100 {
+		t.Errorf("Position() after attach = %d, want %d", s.Position(), target+100)
+	}
+}
+
+func TestBytesFor(t *testing.T) {
+	buf := newStreamBuffer(200 * 417)
+	if got := buf.bytesFor(time.Second); got != -1 {
+		t.Errorf("bytesFor before any frame = %d, want -1", got)
+	}
+	// One second at 44.1 kHz is 38.3 frames, so 39 whole frames
+	buf.append(bytes.NewReader(silentMP3(10)))
+	if got, want := buf.bytesFor(time.Second), int64(39*417); got != want {
+		t.Errorf("bytesFor(1s) from 10 frames = %d, want %d", got, want)
+	}
+	buf.append(bytes.NewReader(silentMP3(50)))
+	if got, want := buf.bytesFor(time.Second), int64(39*417); got != want {
+		t.Errorf("bytesFor(1s) once downloaded = %d, want %d", got, want)
+	}
+	if got, want := buf.bytesFor(time.Minute), int64(200*417); got != want {
+		t.Errorf("bytesFor past the end = %d, want the size %d", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package player
+
+import (
+	"github.com/gopxl/beep/v2"
+
+	"github.com/dangerous-person/dopogoto/internal/dsp"
+)
+
+const (
+	stretchFrame = 2048             // samples per grain, ~46 ms at 44.1 kHz
+	stretchHop   = stretchFrame / 2 // output samples between grains
+	stretchTol   = 512              // how far a grain may shift to line up, ~12 ms
+	stretchChunk = 512              // samples read from the source at a time
+)
+
+// stretcher changes tempo without changing pitch (WSOLA). It cuts the input
+// into overlapping grains taken speed*stretchHop apart and lays them down
+// stretchHop apart, nudging each grain to where it best continues the
+// previous one so the waveform doesn't jump.
+type stretcher struct {
+	src   beep.Streamer
+	speed float64
+	win   []float64
+
+	in      [][2]float64 // buffered input, starting at input sample inStart
+	inStart int
+	srcDone bool
+
+	pos     float64 // nominal input position of the next grain
+	prev    int     // input position of the last grain, -1 before the first
+	ola     [stretchFrame][2]float64
+	out     [][2]float64 // finished output not streamed yet
+	flushed bool
+}
+
+func newStretcher(src beep.Streamer, speed float64) *stretcher {
+	return &stretcher{src: src, speed: speed, win: dsp.Hann(stretchFrame), prev: -1}
+}
+
+func (s *stretcher) Stream(samples [][2]float64) (int, bool) {
+	filled := 0
+	for filled < len(samples) {
+		if len(s.out) == 0 && !s.grain() {
+			break
+		}
+		n := copy(samples[filled:], s.out)
+		s.out = s.out[n:]
+		filled += n
+	}
+	return filled, filled > 0
+}
+
+func (s *stretcher) Err() error {
+	return s.src.Err()
+}
+
+// grain adds the next grain and moves a hop of finished output to s.out.
+// It returns false once everything has been output.
+func (s *stretcher) grain() bool {
+	start := int(s.pos)
+	s.fill(start + stretchTol + stretchFrame)
+	if s.srcDone && start >= s.inStart+len(s.in) {
+		if s.flushed {
+			return false
+		}
+		// The last grain's tail
+		s.flushed = true
+		s.out = append(s.out[:0], s.ola[:stretchHop]...)
+		return true
+	}
+
+	best := start
+	if s.prev >= 0 {
+		best = s.align(s.prev+stretchHop, max(start-stretchTol, s.inStart), start+stretchTol)
+	}
+	for i := range s.ola {
+		if j := best - s.inStart + i; j < len(s.in) {
+			s.ola[i][0] += s.in[j][0] * s.win[i]
+			s.ola[i][1] += s.in[j][1] * s.win[i]
+		}
+	}
+	s.prev = best
+	s.pos += s.speed * stretchHop
+
+	s.out = append(s.out[:0], s.ola[:stretchHop]...)
+	copy(s.ola[:], s.ola[stretchHop:])
+	clear(s.ola[stretchFrame-stretchHop:])
+
+	// Drop input no later grain can reach
+	if drop := min(s.prev+stretchHop, int(s.pos)-stretchTol) - s.inStart; drop > 4*stretchFrame {
+		s.in = append(s.in[:0], s.in[drop:]...)
+		s.inStart += drop
+	}
+	return true
+}
+
+// align returns the grain start between lo and hi that best matches the
+// input at target, the natural continuation of the previous grain. A coarse
+// pass on every fourth sample is refined around its winner.
+func (s *stretcher) align(target, lo, hi int) int {
+	best, bestCorr := lo, -1e300
+	try := func(c, stride int) {
+		var corr float64
+		for i := 0; i < stretchHop; i += stride {
+			corr += s.mono(target+i) * s.mono(c+i)
+		}
+		if corr > bestCorr {
+			best, bestCorr = c, corr
+		}
+	}
+	for c := lo; c <= hi; c += 4 {
+		try(c, 4)
+	}
+	coarse := best
+	bestCorr = -1e300
+	for c := max(lo, coarse-3); c <= min(hi, coarse+3); c++ {
+		try(c, 1)
+	}
+	return best
+}
+
+// mono returns input sample i mixed to mono, 0 outside the buffer.
+func (s *stretcher) mono(i int) float64 {
+	i -= s.inStart
+	if i < 0 || i >= len(s.in) {
+		return 0
+	}
+	return s.in[i][0] + s.in[i][1]
+}
+
+// fill reads from the source until the buffer reaches input sample upto.
+func (s *stretcher) fill(upto int) {
+	var buf [stretchChunk][2]float64
+	for !s.srcDone && s.inStart+len(s.in) < upto {
+		n, ok := s.src.Stream(buf[:])
+		s.in = append(s.in, buf[:n]...)
+		if !ok {
+			s.srcDone = true
+		}
+	}
+}
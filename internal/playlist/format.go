@@ -0,0 +1,132 @@
+package playlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+)
+
+// readM3U reads an extended M3U playlist: a location per line, each
+// optionally preceded by "#EXTINF:seconds,title", and the playlist's name
+// from a "#PLAYLIST:" line if it has one.
+func readM3U(r io.Reader, base string) (name string, tracks []data.Track, err error) {
+	var pending data.Track
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			secs, title, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			// Attributes like tvg-id="…" may follow the length
+			secs, _, _ = strings.Cut(secs, " ")
+			pending = data.Track{Title: strings.TrimSpace(title)}
+			if n, err := strconv.Atoi(secs); err == nil && n > 0 {
+				pending.Duration = time.Duration(n) * time.Second
+			}
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#"):
+		default:
+			t := pending
+			t.URL = resolve(line, base)
+			if t.Title == "" {
+				t.Title = fallbackTitle(t.URL)
+			}
+			tracks = append(tracks, t)
+			pending = data.Track{}
+		}
+	}
+	return name, tracks, sc.Err()
+}
+
+// writeM3U writes an extended M3U playlist, with a "#PLAYLIST:" line
+// unless name is empty.
+func writeM3U(w io.Writer, name string, tracks []data.Track, dir string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	if name != "" {
+		fmt.Fprintf(bw, "#PLAYLIST:%s\n", name)
+	}
+	for _, t := range tracks {
+		fmt.Fprintf(bw, "#EXTINF:%s,%s\n", seconds(t), t.Title)
+		fmt.Fprintln(bw, location(t.URL, dir))
+	}
+	return bw.Flush()
+}
+
+// readPLS reads a PLS playlist: an INI-style [playlist] section of FileN,
+// TitleN and LengthN keys.
+func readPLS(r io.Reader, base string) ([]data.Track, error) {
+	entries := map[int]*data.Track{}
+	entry := func(n int) *data.Track {
+		if entries[n] == nil {
+			entries[n] = &data.Track{}
+		}
+		return entries[n]
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		for _, field := range []string{"file", "title", "length"} {
+			n, err := strconv.Atoi(strings.TrimPrefix(key, field))
+			if !strings.HasPrefix(key, field) || err != nil {
+				continue
+			}
+			t := entry(n)
+			switch field {
+			case "file":
+				t.URL = resolve(value, base)
+			case "title":
+				t.Title = value
+			case "length":
+				if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+					t.Duration = time.Duration(secs) * time.Second
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	nums := make([]int, 0, len(entries))
+	for n, t := range entries {
+		if t.URL != "" {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	tracks := make([]data.Track, 0, len(nums))
+	for _, n := range nums {
+		t := *entries[n]
+		if t.Title == "" {
+			t.Title = fallbackTitle(t.URL)
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
+
+func writePLS(w io.Writer, tracks []data.Track, dir string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[playlist]")
+	for i, t := range tracks {
+		fmt.Fprintf(bw, "File%d=%s\n", i+1, location(t.URL, dir))
+		fmt.Fprintf(bw, "Title%d=%s\n", i+1, t.Title)
+		fmt.Fprintf(bw, "Length%d=%s\n", i+1, seconds(t))
+	}
+	fmt.Fprintf(bw, "NumberOfEntries=%d\n", len(tracks))
+	fmt.Fprintln(bw, "Version=2")
+	return bw.Flush()
+}
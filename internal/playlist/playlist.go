@@ -0,0 +1,206 @@
+// Package playlist keeps user playlists, saved as M3U8 files under the
+// config dir, and reads and writes M3U and PLS files for sharing them with
+// other players.
+package playlist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dangerous-person/dopogoto/internal/config"
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/library"
+	"github.com/dangerous-person/dopogoto/internal/urlpath"
+)
+
+// Genre is given to every playlist shown in the album list.
+const Genre = "Playlist"
+
+// Playlist is a named list of catalog and local tracks.
+type Playlist struct {
+	Name   string
+	Tracks []data.Track
+}
+
+// Album returns the playlist as an album for the album list.
+func (p Playlist) Album() data.Album {
+	return data.Album{Title: p.Name, Genre: Genre, Tracks: append([]data.Track(nil), p.Tracks...)}
+}
+
+// Dir returns where playlists are saved.
+func Dir() string {
+	return filepath.Join(config.Dir(), "playlists")
+}
+
+// errFormat is returned for a file that is neither M3U nor PLS.
+var errFormat = errors.New("playlist: not an .m3u, .m3u8 or .pls file")
+
+// LoadAll reads every playlist saved in dir, sorted by name. Files that
+// can't be read are skipped.
+func LoadAll(dir string) []Playlist {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var lists []Playlist
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".m3u8" {
+			continue
+		}
+		if p, err := Import(filepath.Join(dir, e.Name())); err == nil {
+			lists = append(lists, p)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return strings.ToLower(lists[i].Name) < strings.ToLower(lists[j].Name)
+	})
+	return lists
+}
+
+// Save writes p to dir, named after the playlist. Names that only differ
+// in their path separators would share a file, so saving p over another
+// playlist fails.
+func Save(dir string, p Playlist) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fileName(p.Name))
+	if old, err := Import(path); err == nil && !strings.EqualFold(old.Name, p.Name) {
+		return fmt.Errorf("playlist: %s is already saved as %s", old.Name, filepath.Base(path))
+	}
+	var buf bytes.Buffer
+	if err := writeM3U(&buf, p.Name, p.Tracks, dir); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Delete removes the playlist called name from dir.
+func Delete(dir, name string) error {
+	return os.Remove(filepath.Join(dir, fileName(name)))
+}
+
+// fileName is the file a playlist is saved as, with path separators
+// replaced so any name is safe. The name itself is kept in the file.
+func fileName(name string) string {
+	return strings.NewReplacer("/", "-", `\`, "-").Replace(name) + ".m3u8"
+}
+
+// Import reads an .m3u, .m3u8 or .pls file, naming the playlist after the
+// file unless it names itself. Relative paths in it are taken from the
+// file's folder.
+func Import(path string) (Playlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Playlist{}, err
+	}
+	defer f.Close()
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	base := filepath.Dir(path)
+	var tracks []data.Track
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8":
+		var own string
+		own, tracks, err = readM3U(f, base)
+		if own != "" {
+			name = own
+		}
+	case ".pls":
+		tracks, err = readPLS(f, base)
+	default:
+		return Playlist{}, errFormat
+	}
+	if err != nil {
+		return Playlist{}, err
+	}
+	return Playlist{Name: name, Tracks: tracks}, nil
+}
+
+// Export writes tracks to an .m3u, .m3u8 or .pls file. Local files inside
+// the file's folder are written as relative paths, others as absolute
+// ones, and catalog tracks as URLs.
+func Export(path string, tracks []data.Track) error {
+	var write func(io.Writer, []data.Track, string) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8":
+		write = func(w io.Writer, tracks []data.Track, dir string) error {
+			return writeM3U(w, "", tracks, dir)
+		}
+	case ".pls":
+		write = writePLS
+	default:
+		return errFormat
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, tracks, filepath.Dir(path)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// resolve turns a playlist entry into a track URL: web URLs as the catalog
+// spells them (unescaped), anything else a local file, relative to base.
+func resolve(loc, base string) string {
+	if strings.HasPrefix(loc, "http://") || strings.HasPrefix(loc, "https://") {
+		u, err := url.Parse(loc)
+		if err != nil {
+			return loc
+		}
+		s := u.Scheme + "://" + u.Host + u.Path
+		if u.RawQuery != "" {
+			s += "?" + u.RawQuery
+		}
+		return s
+	}
+	if path, ok := strings.CutPrefix(loc, "file://"); ok {
+		if p, err := url.PathUnescape(path); err == nil {
+			path = p
+		}
+		loc = path
+	}
+	if !filepath.IsAbs(loc) {
+		loc = filepath.Join(base, loc)
+	}
+	return library.FileURL(filepath.Clean(loc))
+}
+
+// location is how a track is written to a playlist file in dir.
+func location(rawURL, dir string) string {
+	path, ok := strings.CutPrefix(rawURL, "file://")
+	if !ok {
+		return urlpath.Encode(rawURL)
+	}
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// fallbackTitle names an entry the playlist gave no title for after its
+// file.
+func fallbackTitle(rawURL string) string {
+	name := rawURL
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// seconds formats a length for a playlist file, -1 when unknown.
+func seconds(t data.Track) string {
+	if t.Duration <= 0 {
+		return "-1"
+	}
+	return fmt.Sprint(int(t.Duration.Seconds()))
+}
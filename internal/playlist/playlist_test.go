@@ -0,0 +1,127 @@
+package playlist
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/library"
+)
+
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	tracks := []data.Track{
+		{Title: "A Song To Take A Nap", Duration: 3*time.Minute + 2*time.Second, URL: "https://cdn.dopogoto.com/Dopo Goto - Pillbox/Dopo Goto - Pillbox - 10 A Song To Take A Nap.mp3"},
+		{Title: "Inside", URL: library.FileURL(filepath.Join(dir, "music", "01 Inside.flac"))},
+		{Title: "Elsewhere", Duration: time.Minute, URL: library.FileURL("/elsewhere/02 Out.ogg")},
+	}
+	for _, ext := range []string{".m3u8", ".m3u", ".pls"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(dir, "Mix"+ext)
+			if err := Export(path, tracks); err != nil {
+				t.Fatal(err)
+			}
+			raw, _ := os.ReadFile(path)
+			if strings.Contains(string(raw), "Dopo Goto - Pillbox/") || !strings.Contains(string(raw), "music/01 Inside.flac") {
+				t.Errorf("want escaped URLs and paths relative to the playlist, got:\n%s", raw)
+			}
+			p, err := Import(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Name != "Mix" {
+				t.Errorf("name %q, want Mix", p.Name)
+			}
+			if !slices.Equal(p.Tracks, tracks) {
+				t.Errorf("read back\n%+v\nwant\n%+v", p.Tracks, tracks)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		file, body string
+		want       []data.Track
+	}{
+		{
+			file: "plain.m3u",
+			body: "# a comment\nsongs/one.mp3\n\n/abs/two.wav\r\nfile:///abs/three%203.ogg\n",
+			want: []data.Track{
+				{Title: "one", URL: library.FileURL(filepath.Join(dir, "songs/one.mp3"))},
+				{Title: "two", URL: library.FileURL("/abs/two.wav")},
+				{Title: "three 3", URL: library.FileURL("/abs/three 3.ogg")},
+			},
+		},
+		{
+			file: "web.m3u8",
+			body: "\ufeff#EXTM3U\n#EXTINF:-1,Streamed\nhttps://example.com/a%20b.mp3\n",
+			want: []data.Track{{Title: "Streamed", URL: "https://example.com/a b.mp3"}},
+		},
+		{
+			file: "out-of-order.pls",
+			body: "[playlist]\nFile2=b.mp3\nTitle2=Bee\nfile1=a.mp3\nLength1=61\nNumberOfEntries=2\n",
+			want: []data.Track{
+				{Title: "a", Duration: 61 * time.Second, URL: library.FileURL(filepath.Join(dir, "a.mp3"))},
+				{Title: "Bee", URL: library.FileURL(filepath.Join(dir, "b.mp3"))},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.body), 0644); err != nil {
+				t.Fatal(err)
+			}
+			p, err := Import(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(p.Tracks, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", p.Tracks, tt.want)
+			}
+		})
+	}
+
+	if _, err := Import(filepath.Join(dir, "list.txt")); err == nil {
+		t.Error("imported a file that isn't a playlist")
+	}
+}
+
+func TestSaveLoadDelete(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "playlists")
+	lists := []Playlist{
+		{Name: "road/trip", Tracks: []data.Track{{Title: "X", URL: "https://cdn.example/x.mp3"}}},
+		{Name: "Evening", Tracks: []data.Track{{Title: "Y", URL: library.FileURL("/m/y.mp3")}}},
+	}
+	for _, p := range lists {
+		if err := Save(dir, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Save(dir, Playlist{Name: "road-trip"}); err == nil {
+		t.Error("saved road-trip over road/trip")
+	}
+	got := LoadAll(dir)
+	if len(got) != 2 || got[0].Name != "Evening" || got[1].Name != "road/trip" {
+		t.Fatalf("loaded %+v, want Evening then road/trip", got)
+	}
+	if !slices.Equal(got[1].Tracks, lists[0].Tracks) {
+		t.Errorf("tracks %+v, want %+v", got[1].Tracks, lists[0].Tracks)
+	}
+	if !slices.Equal(got[0].Tracks, lists[1].Tracks) {
+		t.Errorf("tracks %+v, want %+v", got[0].Tracks, lists[1].Tracks)
+	}
+
+	if err := Delete(dir, "Evening"); err != nil {
+		t.Fatal(err)
+	}
+	if got := LoadAll(dir); len(got) != 1 {
+		t.Errorf("after delete loaded %+v", got)
+	}
+}
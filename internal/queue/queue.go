@@ -0,0 +1,95 @@
+// Package queue holds the tracks lined up to play before the player goes
+// back to album order or shuffle.
+package queue
+
+// Entry is a queued track, by its place in the album list.
+type Entry struct {
+	Album int // index in the album list
+	Track int // index in the album
+}
+
+// Queue is an ordered list of tracks to play next. The zero value is an
+// empty queue.
+type Queue struct {
+	entries []Entry
+}
+
+// Len returns how many tracks are queued.
+func (q *Queue) Len() int {
+	return len(q.entries)
+}
+
+// Entries returns the queued tracks in play order. The slice must not be
+// modified.
+func (q *Queue) Entries() []Entry {
+	return q.entries
+}
+
+// Add puts a track at the end of the queue.
+func (q *Queue) Add(e Entry) {
+	q.entries = append(q.entries, e)
+}
+
+// PlayNext puts a track at the front of the queue.
+func (q *Queue) PlayNext(e Entry) {
+	q.entries = append([]Entry{e}, q.entries...)
+}
+
+// Peek returns the track at the front of the queue.
+func (q *Queue) Peek() (Entry, bool) {
+	if len(q.entries) == 0 {
+		return Entry{}, false
+	}
+	return q.entries[0], true
+}
+
+// Pop removes and returns the track at the front of the queue.
+func (q *Queue) Pop() (Entry, bool) {
+	e, ok := q.Peek()
+	if ok {
+		q.entries = q.entries[1:]
+	}
+	return e, ok
+}
+
+// Remove drops the i'th queued track. Out of range indexes are ignored.
+func (q *Queue) Remove(i int) {
+	if i < 0 || i >= len(q.entries) {
+		return
+	}
+	q.entries = append(q.entries[:i:i], q.entries[i+1:]...)
+}
+
+// Move shifts the i'th queued track to position j, reporting whether both
+// were in range.
+func (q *Queue) Move(i, j int) bool {
+	if i < 0 || i >= len(q.entries) || j < 0 || j >= len(q.entries) {
+		return false
+	}
+	e := q.entries[i]
+	if i < j {
+		copy(q.entries[i:j], q.entries[i+1:j+1])
+	} else {
+		copy(q.entries[j+1:i+1], q.entries[j:i])
+	}
+	q.entries[j] = e
+	return true
+}
+
+// Remap rewrites each queued track with fn, dropping those it reports
+// false for. It's for keeping the queue in step when albums or tracks are
+// removed from the list.
+func (q *Queue) Remap(fn func(Entry) (Entry, bool)) {
+	kept := make([]Entry, 0, len(q.entries))
+	for _, e := range q.entries {
+		if e, ok := fn(e); ok {
+			kept = append(kept, e)
+		}
+	}
+	q.entries = kept
+}
+
+// Clear empties the queue.
+func (q *Queue) Clear() {
+	q.entries = nil
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+)
+
+// tracks is a queue of tracks 0..n-1 of album 0.
+func tracks(n int) *Queue {
+	q := &Queue{}
+	for i := range n {
+		q.Add(Entry{Track: i})
+	}
+	return q
+}
+
+// order lists the queued track numbers.
+func order(q *Queue) []int {
+	var out []int
+	for _, e := range q.Entries() {
+		out = append(out, e.Track)
+	}
+	return out
+}
+
+func TestQueue(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(q *Queue)
+		want []int
+	}{
+		{"add", func(q *Queue) { q.Add(Entry{Track: 9}) }, []int{0, 1, 2, 3, 9}},
+		{"play next", func(q *Queue) { q.PlayNext(Entry{Track: 9}) }, []int{9, 0, 1, 2, 3}},
+		{"pop", func(q *Queue) { q.Pop() }, []int{1, 2, 3}},
+		{"remove", func(q *Queue) { q.Remove(1) }, []int{0, 2, 3}},
+		{"remove last", func(q *Queue) { q.Remove(3) }, []int{0, 1, 2}},
+		{"remove out of range", func(q *Queue) { q.Remove(4) }, []int{0, 1, 2, 3}},
+		{"move down", func(q *Queue) { q.Move(0, 2) }, []int{1, 2, 0, 3}},
+		{"move up", func(q *Queue) { q.Move(3, 1) }, []int{0, 3, 1, 2}},
+		{"move in place", func(q *Queue) { q.Move(2, 2) }, []int{0, 1, 2, 3}},
+		{"move out of range", func(q *Queue) { q.Move(0, 4) }, []int{0, 1, 2, 3}},
+		{"remap", func(q *Queue) {
+			q.Remap(func(e Entry) (Entry, bool) {
+				e.Track *= 10
+				return e, e.Track != 20
+			})
+		}, []int{0, 10, 30}},
+		{"clear", func(q *Queue) { q.Clear() }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tracks(4)
+			tt.op(q)
+			if got := order(q); !slices.Equal(got, tt.want) {
+				t.Errorf("queue %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPopEmpty(t *testing.T) {
+	q := tracks(1)
+	if e, ok := q.Pop(); !ok || e.Track != 0 {
+		t.Fatalf("Pop() = %v, %v; want track 0", e, ok)
+	}
+	if _, ok := q.Pop(); ok {
+		t.Error("Pop() on an empty queue reported a track")
+	}
+	if _, ok := q.Peek(); ok {
+		t.Error("Peek() on an empty queue reported a track")
+	}
+}
+
+func TestRemoveKeepsEntries(t *testing.T) {
+	// Removing must not write through to a slice handed out earlier.
+	q := tracks(3)
+	before := q.Entries()
+	q.Remove(0)
+	if !slices.Equal(order(&Queue{entries: before}), []int{0, 1, 2}) {
+		t.Errorf("earlier Entries() changed to %v", before)
+	}
+}
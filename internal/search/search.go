@@ -0,0 +1,98 @@
+// Package search fuzzy-matches queries against album and track titles.
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Item is something that can be searched for.
+type Item struct {
+	Text  string
+	Album int // album list index
+	Track int // track index, or -1 for the album itself
+}
+
+// Match is an item that matched, with how well.
+type Match struct {
+	Item
+	Score int
+}
+
+// Score rates how well query fuzzy-matches text, ignoring case. Every
+// word of the query has to appear in text in order, though not
+// necessarily together; runs of letters, matches at the start of words
+// and shorter texts score higher. ok is false if it doesn't match.
+func Score(query, text string) (score int, ok bool) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return 0, false
+	}
+	lower := []rune(strings.ToLower(text))
+	for _, w := range words {
+		s, ok := scoreWord([]rune(w), lower)
+		if !ok {
+			return 0, false
+		}
+		score += s
+	}
+	return score - len(lower)/8, true
+}
+
+// scoreWord matches the runes of word in order in text, taking the best
+// scoring place to start it.
+func scoreWord(word, text []rune) (int, bool) {
+	best, found := 0, false
+	for start := range text {
+		if text[start] != word[0] {
+			continue
+		}
+		if s, ok := scoreFrom(word, text, start); ok && (!found || s > best) {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// scoreFrom matches word greedily in text starting at start.
+func scoreFrom(word, text []rune, start int) (int, bool) {
+	score, prev := 0, -1
+	ti := start
+	for _, r := range word {
+		for ti < len(text) && text[ti] != r {
+			ti++
+		}
+		if ti == len(text) {
+			return 0, false
+		}
+		score++
+		switch {
+		case prev >= 0 && ti == prev+1:
+			score += 5 // continues a run
+		case prev >= 0:
+			score -= 3 + min(ti-prev-1, 5) // a gap
+		}
+		if ti == 0 || !unicode.IsLetter(text[ti-1]) && !unicode.IsDigit(text[ti-1]) {
+			score += 8 // starts a word
+		}
+		prev = ti
+		ti++
+	}
+	return score, true
+}
+
+// Rank returns the items query matches, best first and at most limit of
+// them. Ties keep the items' order.
+func Rank(query string, items []Item, limit int) []Match {
+	var matches []Match
+	for _, it := range items {
+		if s, ok := Score(query, it.Text); ok {
+			matches = append(matches, Match{Item: it, Score: s})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches[:min(limit, len(matches))]
+}
@@ -0,0 +1,62 @@
+package search
+
+import "testing"
+
+func TestScoreMatches(t *testing.T) {
+	tests := []struct {
+		query, text string
+		want        bool
+	}{
+		{"cognitive", "A Song To Spot A Cognitive Distortion", true},
+		{"COGDIS", "A Song To Spot A Cognitive Distortion", true},
+		{"spot distortion", "A Song To Spot A Cognitive Distortion", true},
+		{"distortion spot", "A Song To Spot A Cognitive Distortion", true},
+		{"sptcog", "A Song To Spot A Cognitive Distortion", true},
+		{"xyz", "A Song To Spot A Cognitive Distortion", false},
+		{"spot zebra", "A Song To Spot A Cognitive Distortion", false},
+		{"", "anything", false},
+		{"   ", "anything", false},
+	}
+	for _, tt := range tests {
+		if _, got := Score(tt.query, tt.text); got != tt.want {
+			t.Errorf("Score(%q, %q) ok = %v, want %v", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	items := []Item{
+		{Text: "A Song To Drive Through The Dunes", Album: 0, Track: 0},
+		{Text: "The Songs From The Disc One", Album: 1, Track: -1},
+		{Text: "A Song To Dance In The Disco", Album: 1, Track: 3},
+		{Text: "A Song About Nothing", Album: 2, Track: 0},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// An unbroken run beats the same letters split up
+		{"disco", []string{"A Song To Dance In The Disco", "The Songs From The Disc One"}},
+		// Ties keep the items' order
+		{"disc", []string{"The Songs From The Disc One", "A Song To Dance In The Disco"}},
+		{"sothdu", []string{"A Song To Drive Through The Dunes"}},
+		{"nothing", []string{"A Song About Nothing"}},
+		{"zzz", nil},
+	}
+	for _, tt := range tests {
+		got := Rank(tt.query, items, 10)
+		if len(got) != len(tt.want) {
+			t.Errorf("Rank(%q) = %d matches, want %d: %+v", tt.query, len(got), len(tt.want), got)
+			continue
+		}
+		for i, m := range got {
+			if m.Text != tt.want[i] {
+				t.Errorf("Rank(%q)[%d] = %q, want %q", tt.query, i, m.Text, tt.want[i])
+			}
+		}
+	}
+
+	if got := Rank("song", items, 2); len(got) != 2 {
+		t.Errorf("Rank with limit 2 returned %d matches", len(got))
+	}
+}
@@ -0,0 +1,118 @@
+// Package shuffle deals tracks in a random order that plays every track
+// once before any comes round again, and keeps the order across restarts.
+package shuffle
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/dangerous-person/dopogoto/internal/config"
+)
+
+// Bag is a shuffled deal of track URLs. Albums are shuffled on every
+// launch, so tracks are kept by URL rather than by list position.
+type Bag struct {
+	path  string
+	Key   string   `json:"key"`   // what the tracks were drawn from
+	Order []string `json:"order"` // track URLs in the order they play
+	Pos   int      `json:"pos"`   // how many of Order have been played
+}
+
+// DefaultPath returns the bag's file in the config dir.
+func DefaultPath() string {
+	return filepath.Join(config.Dir(), "shuffle.json")
+}
+
+// Open loads the bag at path. A missing or unreadable file starts empty.
+func Open(path string) *Bag {
+	b := &Bag{}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, b)
+	}
+	b.path = path
+	b.Pos = max(0, min(b.Pos, len(b.Order)))
+	return b
+}
+
+// Peek returns the track that plays next from urls, the tracks key
+// stands for. A new key, or a bag that has been played through, deals
+// the tracks afresh; tracks no longer in urls are passed over. ok is
+// false if urls is empty.
+func (b *Bag) Peek(key string, urls []string) (url string, ok bool) {
+	if len(urls) == 0 {
+		return "", false
+	}
+	if b.Key != key {
+		b.deal(key, urls, "")
+	}
+	for b.Pos < len(b.Order) && !slices.Contains(urls, b.Order[b.Pos]) {
+		b.Pos++
+	}
+	if b.Pos == len(b.Order) {
+		last := ""
+		if len(b.Order) > 0 {
+			last = b.Order[len(b.Order)-1]
+		}
+		b.deal(key, urls, last)
+	}
+	return b.Order[b.Pos], true
+}
+
+// Played moves past url if it's the next track in the bag.
+func (b *Bag) Played(url string) {
+	if b.Pos < len(b.Order) && b.Order[b.Pos] == url {
+		b.Pos++
+	}
+}
+
+// Upcoming returns up to n of the tracks still to play from urls, in
+// order.
+func (b *Bag) Upcoming(key string, urls []string, n int) []string {
+	if b.Key != key {
+		return nil
+	}
+	var next []string
+	for _, url := range b.Order[b.Pos:] {
+		if len(next) == n {
+			break
+		}
+		if slices.Contains(urls, url) {
+			next = append(next, url)
+		}
+	}
+	return next
+}
+
+// deal shuffles urls into a new order, keeping last, the track that just
+// played, off the front so it doesn't play twice running.
+func (b *Bag) deal(key string, urls []string, last string) {
+	b.Key = key
+	b.Order = slices.Clone(urls)
+	b.Pos = 0
+	rand.Shuffle(len(b.Order), func(i, j int) {
+		b.Order[i], b.Order[j] = b.Order[j], b.Order[i]
+	})
+	if len(b.Order) > 1 && b.Order[0] == last {
+		j := 1 + rand.Intn(len(b.Order)-1)
+		b.Order[0], b.Order[j] = b.Order[j], b.Order[0]
+	}
+}
+
+// Save writes the bag.
+func (b *Bag) Save() error {
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
@@ -0,0 +1,100 @@
+package shuffle
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func urls(n int) []string {
+	var u []string
+	for i := range n {
+		u = append(u, fmt.Sprintf("https://cdn/%02d.mp3", i))
+	}
+	return u
+}
+
+// play deals n tracks from the bag.
+func play(b *Bag, key string, tracks []string, n int) []string {
+	var got []string
+	for range n {
+		url, ok := b.Peek(key, tracks)
+		if !ok {
+			break
+		}
+		b.Played(url)
+		got = append(got, url)
+	}
+	return got
+}
+
+func TestEveryTrackOnceBeforeRepeats(t *testing.T) {
+	tracks := urls(20)
+	b := Open(filepath.Join(t.TempDir(), "shuffle.json"))
+	for round := range 50 {
+		got := play(b, "all", tracks, len(tracks))
+		sorted := slices.Sorted(slices.Values(got))
+		if !slices.Equal(sorted, tracks) {
+			t.Fatalf("round %d dealt %q, want each track once", round, got)
+		}
+		// No track plays twice running across the reshuffle
+		if next, _ := b.Peek("all", tracks); next == got[len(got)-1] {
+			t.Fatalf("round %d: %s played twice running", round, next)
+		}
+	}
+}
+
+func TestPeekDoesNotAdvance(t *testing.T) {
+	tracks := urls(5)
+	b := Open(filepath.Join(t.TempDir(), "shuffle.json"))
+	first, _ := b.Peek("all", tracks)
+	if again, _ := b.Peek("all", tracks); again != first {
+		t.Errorf("second Peek = %s, want %s", again, first)
+	}
+	b.Played("https://cdn/elsewhere.mp3") // picked by hand, not from the bag
+	if again, _ := b.Peek("all", tracks); again != first {
+		t.Errorf("Peek after another track played = %s, want %s", again, first)
+	}
+}
+
+func TestNewKeyDealsAfresh(t *testing.T) {
+	b := Open(filepath.Join(t.TempDir(), "shuffle.json"))
+	play(b, "album:One", urls(3), 2)
+	other := []string{"https://cdn/x.mp3", "https://cdn/y.mp3"}
+	got := play(b, "album:Two", other, 2)
+	if !slices.Equal(slices.Sorted(slices.Values(got)), other) {
+		t.Errorf("after changing key dealt %q, want %q", got, other)
+	}
+}
+
+func TestRemovedTracksPassedOver(t *testing.T) {
+	tracks := urls(6)
+	b := Open(filepath.Join(t.TempDir(), "shuffle.json"))
+	b.Peek("all", tracks)
+	left := tracks[:3]
+	got := play(b, "all", left, 3)
+	if !slices.Equal(slices.Sorted(slices.Values(got)), left) {
+		t.Errorf("dealt %q, want only %q", got, left)
+	}
+	if up := b.Upcoming("all", left, 10); len(up) != 0 {
+		t.Errorf("Upcoming after the remaining tracks played = %q, want none", up)
+	}
+	if _, ok := b.Peek("all", nil); ok {
+		t.Error("Peek with no tracks is ok")
+	}
+}
+
+func TestBagPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shuffle.json")
+	tracks := urls(8)
+	b := Open(path)
+	play(b, "all", tracks, 3)
+	want := b.Upcoming("all", tracks, 5)
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := Open(path).Upcoming("all", tracks, 5); !slices.Equal(got, want) {
+		t.Errorf("reopened bag has %q to come, want %q", got, want)
+	}
+}
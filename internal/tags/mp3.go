@@ -0,0 +1,194 @@
+package tags
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/dangerous-person/dopogoto/internal/mpeg"
+)
+
+// id3Frames maps ID3v2.3/2.4 frame IDs, and their three-letter v2.2 forms,
+// to tag names.
+var id3Frames = map[string]string{
+	"TIT2": "title", "TT2": "title",
+	"TPE1": "artist", "TP1": "artist",
+	"TALB": "album", "TAL": "album",
+	"TRCK": "track", "TRK": "track",
+	"TYER": "year", "TDRC": "year", "TYE": "year",
+}
+
+// frameScan is how far past the tag to look for the first audio frame.
+const frameScan = 4096
+
+// maxTag caps how much of an ID3 tag is read. The text frames come first;
+// what's cut off is usually cover art.
+const maxTag = 64 << 10
+
+// readMP3 reads an ID3v2 tag and works out the length from the first audio
+// frame: its Xing or VBRI header's frame count if there is one, the tag's
+// TLEN if not, or else the bitrate and file size.
+func readMP3(r io.ReaderAt, size int64) (Info, error) {
+	var info Info
+	var tagLen time.Duration
+
+	audio := int64(0)
+	hdr := make([]byte, 10)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return info, err
+	}
+	if string(hdr[:3]) == "ID3" {
+		n := synchsafe(hdr[6:10])
+		tag := make([]byte, min(n, maxTag))
+		if _, err := r.ReadAt(tag, 10); err != nil && err != io.EOF {
+			return info, err
+		}
+		tagLen = readID3(hdr[3], hdr[5], tag, &info)
+		audio = 10 + n
+		if hdr[5]&0x10 != 0 { // footer
+			audio += 10
+		}
+	}
+
+	buf := make([]byte, frameScan)
+	n, err := r.ReadAt(buf, audio)
+	if err != nil && err != io.EOF {
+		return info, err
+	}
+	buf = buf[:n]
+	for i := 0; i+4 <= len(buf); i++ {
+		hdr, ok := mpeg.ParseHeader(buf[i:])
+		if !ok {
+			continue
+		}
+		if frames, ok := vbrFrames(buf[i:], hdr.Side); ok {
+			info.Duration = time.Duration(frames) * time.Duration(hdr.Samples) * time.Second / time.Duration(hdr.SampleRate)
+		} else if tagLen > 0 {
+			info.Duration = tagLen
+		} else if size > 0 {
+			info.Duration = time.Duration(size-audio-int64(i)) * 8 * time.Second / time.Duration(hdr.Bitrate*1000)
+		}
+		return info, nil
+	}
+	if tagLen > 0 {
+		info.Duration = tagLen
+		return info, nil
+	}
+	return info, errFormat
+}
+
+// vbrFrames reads the frame count from a Xing/Info header after the side
+// info, or a VBRI header at its fixed offset.
+func vbrFrames(frame []byte, side int) (int, bool) {
+	if x := frame[min(4+side, len(frame)):]; len(x) >= 12 && (string(x[:4]) == "Xing" || string(x[:4]) == "Info") {
+		if binary.BigEndian.Uint32(x[4:8])&1 != 0 {
+			return int(binary.BigEndian.Uint32(x[8:12])), true
+		}
+	}
+	if v := frame[min(36, len(frame)):]; len(v) >= 18 && string(v[:4]) == "VBRI" {
+		return int(binary.BigEndian.Uint32(v[14:18])), true
+	}
+	return 0, false
+}
+
+// readID3 fills info from the frames of an ID3v2 tag and returns the
+// length from its TLEN frame, if any.
+func readID3(major, flags byte, tag []byte, info *Info) (length time.Duration) {
+	if flags&0x80 != 0 && major < 4 {
+		tag = unsynchronise(tag)
+	}
+	if flags&0x40 != 0 && len(tag) >= 4 { // extended header
+		skip := int64(binary.BigEndian.Uint32(tag)) + 4
+		if major == 4 {
+			skip = synchsafe(tag)
+		}
+		tag = tag[min(skip, int64(len(tag))):]
+	}
+	idLen, hdrLen := 4, 10
+	if major == 2 {
+		idLen, hdrLen = 3, 6
+	}
+	for len(tag) >= hdrLen && tag[0] != 0 {
+		id := string(tag[:idLen])
+		var size int64
+		switch major {
+		case 2:
+			size = int64(tag[3])<<16 | int64(tag[4])<<8 | int64(tag[5])
+		case 3:
+			size = int64(binary.BigEndian.Uint32(tag[4:8]))
+		default:
+			size = synchsafe(tag[4:8])
+		}
+		if size > int64(len(tag)-hdrLen) {
+			break
+		}
+		body := tag[hdrLen : hdrLen+int(size)]
+		tag = tag[hdrLen+int(size):]
+
+		if id == "TLEN" || id == "TLE" {
+			if ms := number(id3Text(body)); ms > 0 {
+				length = time.Duration(ms) * time.Millisecond
+			}
+		} else if key, ok := id3Frames[id]; ok {
+			info.set(key, id3Text(body))
+		}
+	}
+	return length
+}
+
+// id3Text decodes a text frame: an encoding byte, then Latin-1, UTF-16
+// with a BOM, UTF-16BE or UTF-8. Only the first of several values is kept.
+func id3Text(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	enc, b := b[0], b[1:]
+	var s string
+	switch enc {
+	case 1, 2:
+		bigEndian := enc == 2
+		if len(b) >= 2 && (b[0] == 0xFE && b[1] == 0xFF || b[0] == 0xFF && b[1] == 0xFE) {
+			bigEndian = b[0] == 0xFE
+			b = b[2:]
+		}
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			if bigEndian {
+				u[i] = binary.BigEndian.Uint16(b[2*i:])
+			} else {
+				u[i] = binary.LittleEndian.Uint16(b[2*i:])
+			}
+		}
+		s = string(utf16.Decode(u))
+	case 3:
+		s = string(b)
+	default:
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		s = string(r)
+	}
+	s, _, _ = strings.Cut(s, "\x00")
+	return s
+}
+
+// synchsafe decodes a 4-byte ID3 size with 7 bits per byte.
+func synchsafe(b []byte) int64 {
+	return int64(b[0]&0x7F)<<21 | int64(b[1]&0x7F)<<14 | int64(b[2]&0x7F)<<7 | int64(b[3]&0x7F)
+}
+
+// unsynchronise undoes ID3 unsynchronisation, dropping the 0x00 stuffed
+// after every 0xFF.
+func unsynchronise(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xFF && i+1 < len(b) && b[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
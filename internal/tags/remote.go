@@ -0,0 +1,113 @@
+package tags
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/dangerous-person/dopogoto/internal/urlpath"
+)
+
+// headBytes is how much of a remote track is fetched up front: enough for
+// the tags and first audio frame of a typical MP3.
+const headBytes = 64 << 10
+
+// remote reads a file over HTTP with Range requests, serving what it can
+// from the head fetched when it was opened.
+type remote struct {
+	ctx  context.Context
+	url  string
+	head []byte
+	size int64
+}
+
+// Fetch reads the tags of a remote track, downloading only its header
+// bytes rather than the whole file.
+func Fetch(ctx context.Context, rawURL string) (Info, error) {
+	r := &remote{ctx: ctx, url: urlpath.Encode(rawURL)}
+	if err := r.open(); err != nil {
+		return Info{}, err
+	}
+	return Read(r, r.size, path.Base(rawURL))
+}
+
+// open fetches the head and learns the file's size.
+func (r *remote) open() error {
+	resp, err := r.get(0, headBytes)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		r.size = totalSize(resp.Header.Get("Content-Range"))
+	case http.StatusOK:
+		// No Range support: read the head and drop the rest.
+		r.size = resp.ContentLength
+	default:
+		return fmt.Errorf("HTTP %d for %s", resp.StatusCode, r.url)
+	}
+	r.head, err = io.ReadAll(io.LimitReader(resp.Body, headBytes))
+	return err
+}
+
+func (r *remote) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) <= int64(len(r.head)) {
+		return copy(p, r.head[off:]), nil
+	}
+	if int64(len(r.head)) == r.size {
+		// The whole file is in the head.
+		if off >= r.size {
+			return 0, io.EOF
+		}
+		return copy(p, r.head[off:]), io.EOF
+	}
+	var eof error
+	if r.size >= 0 && off+int64(len(p)) > r.size {
+		if off >= r.size {
+			return 0, io.EOF
+		}
+		p, eof = p[:r.size-off], io.EOF
+	}
+	resp, err := r.get(off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("HTTP %d for %s", resp.StatusCode, r.url)
+	}
+	n, err := io.ReadFull(resp.Body, p)
+	if err == nil {
+		err = eof
+	}
+	return n, err
+}
+
+// get requests n bytes from off.
+func (r *remote) get(off, n int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, "GET", r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+n-1))
+	return http.DefaultClient.Do(req)
+}
+
+// totalSize reads the size from a Content-Range like "bytes 0-99/1234",
+// or -1 if it isn't given.
+func totalSize(contentRange string) int64 {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,158 @@
+// Package tags reads track metadata: ID3v2 and Vorbis comments for the
+// title, artist and so on, and the stream headers that give a track's
+// length without decoding it.
+package tags
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Info is what a track's tags say about it. Fields the tags leave out are
+// zero.
+type Info struct {
+	Title    string        `json:"title,omitempty"`
+	Artist   string        `json:"artist,omitempty"`
+	Album    string        `json:"album,omitempty"`
+	Track    int           `json:"track,omitempty"` // number on the album
+	Year     int           `json:"year,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// errFormat is returned for a file that isn't the format its name says.
+var errFormat = errors.New("tags: unrecognized file")
+
+// Read reads the tags of a size-byte file called name, choosing the format
+// by its extension: FLAC, Ogg Vorbis, WAV, or MP3 for anything else.
+func Read(r io.ReaderAt, size int64, name string) (Info, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".flac":
+		return readFLAC(r)
+	case ".ogg", ".oga":
+		return readOgg(r, size)
+	case ".wav":
+		return readWAV(r)
+	}
+	return readMP3(r, size)
+}
+
+// ReadFile reads the tags of a file on disk.
+func ReadFile(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+	return Read(f, st.Size(), path)
+}
+
+// number reads the leading digits of a tag like "3/12" or "2019-05-01".
+func number(s string) int {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// set stores a tag value in info by its common name.
+func (info *Info) set(key, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	switch key {
+	case "title":
+		info.Title = value
+	case "artist":
+		info.Artist = value
+	case "album":
+		info.Album = value
+	case "track":
+		info.Track = number(value)
+	case "year":
+		info.Year = number(value)
+	}
+}
+
+// Store maps track URLs to their tags and persists them as JSON, so remote
+// tracks only have to be read once. A nil *Store is valid and remembers
+// nothing.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Info
+}
+
+// DefaultPath returns the store's file under the user cache dir.
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "dopogoto", "tags.json")
+}
+
+// Open loads the store at path. A missing or unreadable file starts empty.
+func Open(path string) *Store {
+	s := &Store{path: path, entries: make(map[string]Info)}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &s.entries)
+	}
+	return s
+}
+
+// Get returns the tags stored for url.
+func (s *Store) Get(url string) (Info, bool) {
+	if s == nil {
+		return Info{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.entries[url]
+	return info, ok
+}
+
+// Put records the tags for url. Call Save to persist them.
+func (s *Store) Put(url string, info Info) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[url] = info
+}
+
+// Save writes the store.
+func (s *Store) Save() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,274 @@
+package tags
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// id3Frame builds an ID3v2.3 (or, with synchsafe sizes, 2.4) text frame.
+func id3Frame(id string, v4 bool, enc byte, text []byte) []byte {
+	body := append([]byte{enc}, text...)
+	size := make([]byte, 4)
+	if v4 {
+		n := len(body)
+		size = []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+	} else {
+		binary.BigEndian.PutUint32(size, uint32(len(body)))
+	}
+	return append(append(append([]byte(id), size...), 0, 0), body...)
+}
+
+// id3Tag wraps frames in an ID3v2 header, with padding after them.
+func id3Tag(major byte, frames ...[]byte) []byte {
+	body := append(bytes.Join(frames, nil), make([]byte, 100)...)
+	n := len(body)
+	hdr := []byte{'I', 'D', '3', major, 0, 0, byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+	return append(hdr, body...)
+}
+
+func utf16BOM(s string) []byte {
+	b := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// mp3Frames is n 128 kbps, 44.1 kHz stereo frames; with xing set the first
+// carries a Xing header counting them.
+func mp3Frames(n int, xing bool) []byte {
+	var b bytes.Buffer
+	for i := range n {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		if i == 0 && xing {
+			copy(frame[36:], "Xing\x00\x00\x00\x01")
+			binary.BigEndian.PutUint32(frame[44:], uint32(n))
+		}
+		b.Write(frame)
+	}
+	return b.Bytes()
+}
+
+func TestReadMP3(t *testing.T) {
+	frames := func(n int) time.Duration { return time.Duration(n) * 1152 * time.Second / 44100 }
+	tests := []struct {
+		name string
+		file []byte
+		want Info
+		tol  time.Duration
+	}{
+		{
+			name: "id3v2.3 with Xing",
+			file: append(id3Tag(3,
+				id3Frame("TIT2", false, 0, []byte("Caf\xe9")),
+				id3Frame("TPE1", false, 1, utf16BOM("Dopo Goto")),
+				id3Frame("TALB", false, 3, []byte("Pillbox\x00")),
+				id3Frame("TRCK", false, 0, []byte("3/12")),
+				id3Frame("TYER", false, 0, []byte("2019")),
+			), mp3Frames(200, true)...),
+			want: Info{Title: "Café", Artist: "Dopo Goto", Album: "Pillbox", Track: 3, Year: 2019, Duration: frames(200)},
+		},
+		{
+			name: "id3v2.4 with TLEN",
+			file: append(id3Tag(4,
+				id3Frame("TIT2", true, 3, []byte("Song")),
+				id3Frame("TDRC", true, 3, []byte("2021-03-04")),
+				id3Frame("TLEN", true, 0, []byte("4321")),
+			), mp3Frames(50, false)...),
+			want: Info{Title: "Song", Year: 2021, Duration: 4321 * time.Millisecond},
+		},
+		{
+			name: "bare CBR",
+			file: mp3Frames(400, false),
+			want: Info{Duration: frames(400)},
+			tol:  50 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(bytes.NewReader(tt.file), int64(len(tt.file)), "a.mp3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := got.Duration - tt.want.Duration; d > tt.tol || d < -tt.tol {
+				t.Errorf("duration %v, want %v", got.Duration, tt.want.Duration)
+			}
+			got.Duration = tt.want.Duration
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Read(bytes.NewReader(make([]byte, 1000)), 1000, "noise.mp3"); err == nil {
+		t.Error("read tags from a file with no audio frames")
+	}
+}
+
+// vorbisComment builds a Vorbis comment block.
+func vorbisComment(fields ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 6)
+	b = append(b, "vendor"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(fields)))
+	for _, f := range fields {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(f)))
+		b = append(b, f...)
+	}
+	return b
+}
+
+func TestReadFLAC(t *testing.T) {
+	block := func(kind byte, last bool, body []byte) []byte {
+		if last {
+			kind |= 0x80
+		}
+		n := len(body)
+		return append([]byte{kind, byte(n >> 16), byte(n >> 8), byte(n)}, body...)
+	}
+	info := make([]byte, 34)
+	// 48 kHz, 2 channels, 16 bits, 480000 samples
+	rate, samples := 48000, 480000
+	info[10], info[11], info[12] = byte(rate>>12), byte(rate>>4), byte(rate<<4)|1<<1
+	info[13] = 15 << 4
+	binary.BigEndian.PutUint32(info[14:], uint32(samples))
+
+	var file []byte
+	file = append(file, "fLaC"...)
+	file = append(file, block(0, false, info)...)
+	file = append(file, block(6, false, make([]byte, 5000))...) // picture
+	file = append(file, block(4, true, vorbisComment("TITLE=Tide", "artist=Sea", "TRACKNUMBER=7", "DATE=2020"))...)
+
+	got, err := Read(bytes.NewReader(file), int64(len(file)), "x.FLAC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Info{Title: "Tide", Artist: "Sea", Track: 7, Year: 2020, Duration: 10 * time.Second}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// oggPage builds an Ogg page holding body.
+func oggPage(granule uint64, body []byte) []byte {
+	var segs []byte
+	n := len(body)
+	for ; n >= 255; n -= 255 {
+		segs = append(segs, 255)
+	}
+	segs = append(segs, byte(n))
+	hdr := append([]byte("OggS\x00\x00"), binary.LittleEndian.AppendUint64(nil, granule)...)
+	hdr = append(hdr, make([]byte, 12)...) // serial, sequence, CRC
+	hdr = append(hdr, byte(len(segs)))
+	return append(append(hdr, segs...), body...)
+}
+
+func TestReadOgg(t *testing.T) {
+	ident := append([]byte("\x01vorbis"), make([]byte, 23)...)
+	ident[11] = 2
+	binary.LittleEndian.PutUint32(ident[12:], 44100)
+	comment := append([]byte("\x03vorbis"), vorbisComment("TITLE=Wave", "ALBUM=Shore")...)
+
+	var file []byte
+	file = append(file, oggPage(0, ident)...)
+	file = append(file, oggPage(0, comment)...)
+	file = append(file, oggPage(44100, make([]byte, 80000))...)
+	file = append(file, oggPage(3*44100, make([]byte, 100))...)
+
+	got, err := Read(bytes.NewReader(file), int64(len(file)), "x.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Info{Title: "Wave", Album: "Shore", Duration: 3 * time.Second}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReadWAV(t *testing.T) {
+	chunk := func(id string, body []byte) []byte {
+		b := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+		b = append(b, body...)
+		if len(body)%2 == 1 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	fmtChunk := make([]byte, 16)
+	binary.LittleEndian.PutUint16(fmtChunk[0:], 1)       // PCM
+	binary.LittleEndian.PutUint16(fmtChunk[2:], 2)       // channels
+	binary.LittleEndian.PutUint32(fmtChunk[4:], 22050)   // rate
+	binary.LittleEndian.PutUint32(fmtChunk[8:], 22050*4) // byte rate
+	info := append([]byte("INFO"), chunk("INAM", []byte("Room\x00"))...)
+	info = append(info, chunk("IART", []byte("Hum\x00"))...)
+
+	body := []byte("WAVE")
+	body = append(body, chunk("fmt ", fmtChunk)...)
+	body = append(body, chunk("LIST", info)...)
+	body = append(body, chunk("data", make([]byte, 22050*4*2))...)
+	file := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	file = append(file, body...)
+
+	got, err := Read(bytes.NewReader(file), int64(len(file)), "x.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Info{Title: "Room", Artist: "Hum", Duration: 2 * time.Second}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFetchReadsOnlyTheHeader(t *testing.T) {
+	file := append(id3Tag(3, id3Frame("TIT2", false, 0, []byte("Far"))), mp3Frames(5000, true)...)
+	var served atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &countingWriter{ResponseWriter: w, n: &served}
+		http.ServeContent(cw, r, "a.mp3", time.Time{}, bytes.NewReader(file))
+	}))
+	defer srv.Close()
+
+	got, err := Fetch(context.Background(), srv.URL+"/Album Name/01 Far.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Far" || got.Duration != 5000*1152*time.Second/44100 {
+		t.Errorf("got %+v", got)
+	}
+	if n := served.Load(); n > headBytes {
+		t.Errorf("served %d of %d bytes, want only the head", n, len(file))
+	}
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.n.Add(int64(n))
+	return n, err
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tags.json")
+	s := Open(path)
+	info := Info{Title: "A", Track: 2, Duration: 3 * time.Minute}
+	s.Put("https://cdn/a/2.mp3", info)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := Open(path).Get("https://cdn/a/2.mp3")
+	if !ok || got != info {
+		t.Errorf("reopened store has %+v, %v; want %+v", got, ok, info)
+	}
+}
@@ -0,0 +1,153 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+// vorbisKeys maps Vorbis comment fields to tag names.
+var vorbisKeys = map[string]string{
+	"TITLE":       "title",
+	"ARTIST":      "artist",
+	"ALBUM":       "album",
+	"TRACKNUMBER": "track",
+	"DATE":        "year",
+}
+
+// oggScan is how much of each end of an Ogg file is read: the start for the
+// headers, the end for the last page's position.
+const oggScan = 64 << 10
+
+// readVorbisComment fills info from a Vorbis comment block, as found in
+// FLAC and Ogg Vorbis: a vendor string, then "KEY=value" entries, all
+// length-prefixed little-endian.
+func readVorbisComment(b []byte, info *Info) {
+	next := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := binary.LittleEndian.Uint32(b)
+		if uint64(n) > uint64(len(b)-4) {
+			return nil, false
+		}
+		field := b[4 : 4+n]
+		b = b[4+n:]
+		return field, true
+	}
+	if _, ok := next(); !ok { // vendor
+		return
+	}
+	if len(b) < 4 {
+		return
+	}
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+	for range count {
+		field, ok := next()
+		if !ok {
+			return
+		}
+		key, value, _ := strings.Cut(string(field), "=")
+		if name, ok := vorbisKeys[strings.ToUpper(key)]; ok {
+			info.set(name, value)
+		}
+	}
+}
+
+// readFLAC walks the metadata blocks: STREAMINFO gives the length and
+// VORBIS_COMMENT the tags. Pictures and other blocks are skipped unread.
+func readFLAC(r io.ReaderAt) (Info, error) {
+	var info Info
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return info, err
+	}
+	if string(magic) != "fLaC" {
+		return info, errFormat
+	}
+	off := int64(4)
+	hdr := make([]byte, 4)
+	for {
+		if _, err := r.ReadAt(hdr, off); err != nil {
+			return info, err
+		}
+		last, kind := hdr[0]&0x80 != 0, hdr[0]&0x7F
+		size := int64(hdr[1])<<16 | int64(hdr[2])<<8 | int64(hdr[3])
+		off += 4
+		if kind == 0 || kind == 4 { // STREAMINFO, VORBIS_COMMENT
+			body := make([]byte, size)
+			if _, err := r.ReadAt(body, off); err != nil {
+				return info, err
+			}
+			if kind == 4 {
+				readVorbisComment(body, &info)
+			} else if len(body) >= 18 {
+				rate := int64(body[10])<<12 | int64(body[11])<<4 | int64(body[12])>>4
+				samples := int64(body[13]&0x0F)<<32 | int64(binary.BigEndian.Uint32(body[14:18]))
+				if rate > 0 {
+					info.Duration = time.Duration(samples) * time.Second / time.Duration(rate)
+				}
+			}
+		}
+		off += size
+		if last {
+			return info, nil
+		}
+	}
+}
+
+// readOgg reads an Ogg Vorbis file's identification header for the sample
+// rate and its comment header for the tags, then takes the length from the
+// granule position of the last page.
+func readOgg(r io.ReaderAt, size int64) (Info, error) {
+	var info Info
+	head := make([]byte, min(size, oggScan))
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return info, err
+	}
+	packets := oggBodies(head)
+	if len(packets) < 16 || string(packets[:7]) != "\x01vorbis" {
+		return info, errFormat
+	}
+	rate := binary.LittleEndian.Uint32(packets[12:16])
+	if i := bytes.Index(packets, []byte("\x03vorbis")); i >= 0 {
+		readVorbisComment(packets[i+7:], &info)
+	}
+
+	tailAt := max(size-oggScan, 0)
+	tail := make([]byte, size-tailAt)
+	if _, err := r.ReadAt(tail, tailAt); err != nil && err != io.EOF {
+		return info, err
+	}
+	if i := bytes.LastIndex(tail, []byte("OggS")); i >= 0 && i+14 <= len(tail) && tail[i+4] == 0 && rate > 0 {
+		granule := binary.LittleEndian.Uint64(tail[i+6 : i+14])
+		info.Duration = time.Duration(granule) * time.Second / time.Duration(rate)
+	}
+	return info, nil
+}
+
+// oggBodies strings together the bodies of the Ogg pages in b, which puts
+// the packets they carry back to back.
+func oggBodies(b []byte) []byte {
+	var out []byte
+	for len(b) >= 27 && string(b[:4]) == "OggS" {
+		segments := int(b[26])
+		if len(b) < 27+segments {
+			break
+		}
+		n := 0
+		for _, s := range b[27 : 27+segments] {
+			n += int(s)
+		}
+		body := b[27+segments:]
+		out = append(out, body[:min(n, len(body))]...)
+		if n > len(body) {
+			break
+		}
+		b = body[n:]
+	}
+	return out
+}
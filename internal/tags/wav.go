@@ -0,0 +1,81 @@
+package tags
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+// infoKeys maps RIFF INFO chunk IDs to tag names.
+var infoKeys = map[string]string{
+	"INAM": "title",
+	"IART": "artist",
+	"IPRD": "album",
+	"ITRK": "track",
+	"ICRD": "year",
+}
+
+// maxChunk caps how much of a LIST chunk is read for tags.
+const maxChunk = 1 << 20
+
+// readWAV walks the RIFF chunks: fmt gives the byte rate, data's size the
+// length, and a LIST INFO chunk the tags.
+func readWAV(r io.ReaderAt) (Info, error) {
+	var info Info
+	hdr := make([]byte, 12)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return info, err
+	}
+	if string(hdr[:4]) != "RIFF" || string(hdr[8:12]) != "WAVE" {
+		return info, errFormat
+	}
+	var byteRate, dataSize int64
+	off := int64(12)
+	chunk := make([]byte, 8)
+	for {
+		if _, err := r.ReadAt(chunk, off); err != nil {
+			break
+		}
+		id, size := string(chunk[:4]), int64(binary.LittleEndian.Uint32(chunk[4:]))
+		off += 8
+		switch id {
+		case "fmt ":
+			fmtChunk := make([]byte, 12)
+			if _, err := r.ReadAt(fmtChunk, off); err == nil {
+				byteRate = int64(binary.LittleEndian.Uint32(fmtChunk[8:12]))
+			}
+		case "data":
+			dataSize = size
+		case "LIST":
+			if size > maxChunk {
+				break
+			}
+			list := make([]byte, size)
+			if _, err := r.ReadAt(list, off); err == nil && len(list) >= 4 && string(list[:4]) == "INFO" {
+				readInfoList(list[4:], &info)
+			}
+		}
+		off += size + size&1 // chunks are padded to even sizes
+	}
+	if byteRate > 0 {
+		info.Duration = time.Duration(dataSize) * time.Second / time.Duration(byteRate)
+	}
+	return info, nil
+}
+
+// readInfoList reads the zero-terminated strings of a LIST INFO chunk.
+func readInfoList(b []byte, info *Info) {
+	for len(b) >= 8 {
+		id, size := string(b[:4]), int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[8:]
+		if size > len(b) {
+			return
+		}
+		if key, ok := infoKeys[id]; ok {
+			value, _, _ := strings.Cut(string(b[:size]), "\x00")
+			info.set(key, value)
+		}
+		b = b[min(size+size&1, len(b)):]
+	}
+}
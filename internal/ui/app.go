@@ -5,17 +5,26 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/dangerous-person/dopogoto/assets"
+	"github.com/dangerous-person/dopogoto/internal/cache"
 	"github.com/dangerous-person/dopogoto/internal/chat"
+	"github.com/dangerous-person/dopogoto/internal/config"
 	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/favorites"
+	"github.com/dangerous-person/dopogoto/internal/loudness"
 	"github.com/dangerous-person/dopogoto/internal/player"
+	"github.com/dangerous-person/dopogoto/internal/playlist"
+	"github.com/dangerous-person/dopogoto/internal/queue"
+	"github.com/dangerous-person/dopogoto/internal/search"
+	"github.com/dangerous-person/dopogoto/internal/shuffle"
+	"github.com/dangerous-person/dopogoto/internal/tags"
 	"github.com/dangerous-person/dopogoto/internal/ui/panels"
 	"github.com/dangerous-person/dopogoto/internal/video"
 
@@ -47,24 +56,53 @@ const (
 
 // App is the root bubbletea model.
 type App struct {
-	video      panels.Video
-	albumList  panels.AlbumList
-	trackList  panels.TrackList
-	chat       panels.Chat
-	controls   panels.Controls
-	player     *player.Player
-	chatClient *chat.Client
-	nickname   string
-	focus      focus
-	width      int
-	height     int
-	ready      bool
-	animTick   int
+	video          panels.Video
+	visualizer     panels.Visualizer
+	albumList      panels.AlbumList
+	trackList      panels.TrackList
+	chat           panels.Chat
+	controls       panels.Controls
+	player         *player.Player
+	chatClient     *chat.Client
+	nickname       string
+	settings       config.Config
+	eq             panels.EQ
+	showEQ         bool
+	queue          queue.Queue
+	queuePanel     panels.Queue
+	showQueue      bool
+	statsPanel     panels.Stats
+	showStats      bool
+	searchPanel    panels.Search
+	showSearch     bool
+	searchItems    []search.Item       // every album and track, indexed when the search opens
+	searchMatches  []search.Match      // what searchPanel lists
+	listening      *listen             // the play under way, for the history
+	playlists      []playlist.Playlist // shown after the other albums, in this order
+	playlistTarget string              // playlist /pl add puts tracks in
+	favorites      *favorites.Store
+	shuffleBag     *shuffle.Bag
+	sleep          sleepTimer
+	resume         *resumePoint
+	unresumed      *config.State // last session's, if its track may turn up in the library scan
+	libraryDirs    []string      // local music folders, scanned once the app starts
+	tags           *tags.Store
+	untagged       []string // catalog tracks whose tags haven't been fetched yet
+	focus          focus
+	width          int
+	height         int
+	ready          bool
+	animTick       int
 
 	// Track navigation state
 	currentAlbumIdx int
 	currentTrackIdx int
 
+	// Track queued to follow the current one (see queueNext)
+	upcomingAlbumIdx int
+	upcomingTrackIdx int
+	hasUpcoming      bool
+
 	version string
 
 	// Too-small screen video
@@ -75,28 +113,60 @@ type App struct {
 	tsFrameDur float64
 }
 
-func NewApp(version string) *App {
+// NewApp builds the app. libraryDirs are folders of local music to list
+// alongside the catalog, on top of those in the settings.
+func NewApp(version string, libraryDirs []string) *App {
 	vid, err := panels.NewVideo(assets.Video001BR, assets.Video002BR, assets.Video003BR, assets.Video004BR, assets.Video005BR, assets.Video006BR, assets.Video007BR, assets.Video008BR, assets.Video009BR, assets.Video010BR, assets.Video011BR, assets.Video012BR, assets.Video013BR, assets.Video014BR, assets.Video015BR)
 	if err != nil {
 		log.Printf("video init: %v", err)
 	}
 
-	albums := data.ShuffledAlbums()
+	cfg := chat.LoadConfig()
+	settings := config.Load()
+
+	// Favorites heads the list; it's filled once the rest are in
+	albums := append([]data.Album{{Title: favoritesTitle, Genre: "Favorites"}}, data.ShuffledAlbums()...)
+	lists := playlist.LoadAll(playlist.Dir())
+	for _, p := range lists {
+		albums = append(albums, p.Album())
+	}
+	tagStore := tags.Open(tags.DefaultPath())
+	untagged := applyTags(albums, tagStore)
 	al := panels.NewAlbumList(albums)
 	al.Focused = true
 
 	tl := panels.NewTrackList()
-	cfg := chat.LoadConfig()
+
+	p := player.New()
+	trackCache, err := cache.New(cache.DefaultDir(), int64(settings.CacheMB)<<20)
+	if err != nil {
+		log.Printf("track cache: %v", err)
+	}
+	p.SetCache(trackCache)
+	p.SetCrossfade(time.Duration(settings.Crossfade) * time.Second)
+	p.SetLoudnessStore(loudness.Open(loudness.DefaultPath()))
+	p.SetNormalization(player.Normalization(settings.Normalize))
+	p.SetEQ(settings.EQ)
+	p.SetSpeedMode(player.SpeedMode(settings.SpeedMode))
 
 	app := &App{
 		video:           vid,
+		visualizer:      panels.NewVisualizer(),
 		albumList:       al,
 		trackList:       tl,
 		chat:            panels.NewChat(),
-		controls:        panels.NewControls(),
-		player:          player.New(),
+		controls:        newControls(settings),
+		player:          p,
 		chatClient:      chat.NewClient(),
 		nickname:        cfg.Nickname,
+		settings:        settings,
+		eq:              newEQPanel(settings),
+		tags:            tagStore,
+		untagged:        untagged,
+		playlists:       lists,
+		favorites:       favorites.Open(favorites.DefaultPath()),
+		shuffleBag:      shuffle.Open(shuffle.DefaultPath()),
+		libraryDirs:     libraryFolders(settings.Library, libraryDirs),
 		focus:           focusAlbums,
 		version:         version,
 		currentAlbumIdx: -1,
@@ -115,11 +185,16 @@ func NewApp(version string) *App {
 		tsDec.ApplyFrame(0)
 	}
 
-	// Show tracks for the initially selected album
-	if sel := al.SelectedAlbum(); sel != nil {
-		app.trackList.SetAlbum(sel)
-		app.trackList.Color = al.SelectedColor()
+	app.trackList.Marks = app.trackMarks
+	app.refreshFavorites()
+
+	// Show tracks for the initially selected album, skipping Favorites
+	// while it's empty
+	if len(app.albumList.Albums[favoritesAlbum].Tracks) == 0 {
+		app.albumList.Select(favoritesAlbum + 1)
 	}
+	app.syncTracks()
+	app.restoreState()
 
 	return app
 }
@@ -135,7 +210,7 @@ func (a *App) SetProgram(p *tea.Program) {
 
 func (a *App) Init() tea.Cmd {
 	a.chatClient.Start()
-	cmds := []tea.Cmd{tickCmd(), a.checkForUpdate()}
+	cmds := []tea.Cmd{tickCmd(), a.checkForUpdate(), fetchTags(a.untagged), scanLibrary(a.libraryDirs)}
 	if os.Getenv("DOPOGOTO_NO_TELEMETRY") == "" {
 		go a.sendTelemetry()
 	}
@@ -200,7 +275,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			msg.Version+" available — curl -fsSL .../install.sh | sh")
 		return a, nil
 
+	case tagsMsg:
+		return a, a.gotTags(msg)
+
+	case libraryMsg:
+		a.addLibrary(msg)
+		return a, nil
+
 	case tickMsg:
+		a.countListen(time.Time(msg))
 		a.video.Tick(33)
 		a.tickTooSmallVideo(33)
 		a.animTick++
@@ -209,13 +292,32 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		// Update controls with player state
 		a.syncControlsFromPlayer()
-		return a, tickCmd()
+		if a.settings.Visualizer != "off" {
+			a.visualizer.Update(a.player.Spectrum(a.visualizer.Bands()))
+		}
+		return a, tea.Batch(tickCmd(), a.tickSleep())
 
 	case player.TrackStartedMsg:
+		// A gapless start means the last track played out
+		a.endListen(msg.Gapless)
+		if msg.Gapless && a.hasUpcoming {
+			// The player already crossed into the queued track
+			a.takeQueued(a.upcomingAlbumIdx, a.upcomingTrackIdx)
+			a.selectPlaying(a.upcomingAlbumIdx, a.upcomingTrackIdx)
+			a.controls.AlbumColor = a.trackList.Color
+		}
+		a.queueNext()
+		a.visualizer.Reseed()
 		a.controls.State = panels.StatePlaying
 		a.controls.TrackTitle = msg.TrackTitle
 		a.controls.Duration = msg.Duration
 		a.controls.Position = 0
+		a.controls.SourceRate = msg.SourceRate
+		a.controls.OutputRate = msg.OutputRate
+		a.controls.LoopA, a.controls.LoopB = -1, -1
+		a.controls.Attempt = 0
+		a.resumeStarted()
+		a.startListen()
 		return a, nil
 
 	case player.ProgressMsg:
@@ -226,19 +328,42 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, nil
 
 	case player.TrackEndMsg:
+		a.endListen(true)
+		if a.sleep.album && a.lastOfAlbum() {
+			a.controls.State = panels.StateStopped
+			return a, a.sleepDone()
+		}
 		return a, a.playNext()
 
+	case player.FadedOutMsg:
+		if a.sleep.fading {
+			return a, a.sleepDone()
+		}
+		return a, nil
+
 	case player.ErrorMsg:
+		a.endListen(false)
 		a.controls.State = panels.StateStopped
 		a.controls.TrackTitle = "Couldn't load — skipping to next"
+		a.controls.Attempt = 0
 		// Auto-skip to next track after error
 		return a, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
 			return player.TrackEndMsg{}
 		})
 
 	case player.BufferingMsg:
-		a.controls.State = panels.StateBuffering
+		// A retry mid-track plays on from what has already arrived.
+		if a.controls.State != panels.StatePlaying && a.controls.State != panels.StatePaused {
+			a.controls.State = panels.StateBuffering
+		}
 		a.controls.TrackTitle = msg.TrackTitle
+		a.controls.Attempt, a.controls.Attempts = msg.Attempt, msg.Attempts
+		return a, nil
+
+	case player.DownloadProgressMsg:
+		a.controls.Downloaded = msg.Received
+		a.controls.DownloadNeeded = msg.Needed
+		a.controls.DownloadRate = msg.Rate
 		return a, nil
 
 	case chat.NewMessagesMsg:
@@ -289,6 +414,8 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		a.video.Width = videoW
 		a.video.Height = videoH
+		a.visualizer.Width = videoW
+		a.visualizer.Height = videoH
 		a.chat.Width = videoW
 		a.chat.Height = chatH
 
@@ -312,11 +439,21 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if a.focus == focusChat {
 			return a.handleChatKey(msg)
 		}
+		if a.showEQ {
+			return a.handleEQKey(msg)
+		}
+		if a.showQueue {
+			return a.handleQueueKey(msg)
+		}
+		if a.showStats {
+			return a.handleStatsKey(msg)
+		}
+		if a.showSearch {
+			return a.handleSearchKey(msg)
+		}
 
 		if isQuit(msg) {
-			a.player.Close()
-			a.chatClient.Stop()
-			return a, tea.Quit
+			return a, a.quit()
 		}
 		switch msg.String() {
 		case "tab":
@@ -343,16 +480,52 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "-":
 			a.player.VolumeDown()
 			a.controls.Volume = a.player.VolumeLevel()
+		case "c":
+			a.cycleGenre()
 		case "s":
 			a.controls.Shuffle = !a.controls.Shuffle
 			if a.controls.Shuffle {
-				a.controls.Repeat = false
+				a.controls.Repeat = panels.RepeatOff
 			}
+			a.queueNext()
+		case "S":
+			a.cycleShuffleScope()
 		case "r":
-			a.controls.Repeat = !a.controls.Repeat
-			if a.controls.Repeat {
+			a.controls.Repeat = a.controls.Repeat.Next()
+			if a.controls.Repeat != panels.RepeatOff {
 				a.controls.Shuffle = false
 			}
+			a.queueNext()
+		case "x":
+			a.cycleCrossfade()
+		case "e":
+			a.showEQ = true
+		case "a":
+			a.enqueue(false)
+		case "A":
+			a.enqueue(true)
+		case "u":
+			a.showQueue = true
+		case "h":
+			a.openStats()
+		case "/":
+			a.openSearch()
+		case "f":
+			a.toggleFavorite()
+		case "1", "2", "3", "4", "5":
+			a.rate(int(msg.String()[0] - '0'))
+		case "v":
+			a.cycleVisualizer()
+		case "[":
+			a.changeSpeed(-speedStep)
+		case "]":
+			a.changeSpeed(speedStep)
+		case "\\":
+			a.toggleSpeedMode()
+		case "z":
+			a.cycleSleep()
+		case "l":
+			a.cycleLoop()
 		case "t":
 			panels.CycleTheme()
 		case ">":
@@ -395,20 +568,27 @@ func (a *App) handleEnter() tea.Cmd {
 }
 
 func (a *App) playSelectedTrack() tea.Cmd {
-	track := a.trackList.SelectedTrack()
-	if track == nil {
+	if a.trackList.SelectedTrack() == nil {
 		return nil
 	}
+	return a.playTrack(a.albumList.Cursor, a.trackList.Cursor)
+}
 
-	a.currentAlbumIdx = a.albumList.Cursor
-	a.currentTrackIdx = a.trackList.Cursor
-	a.trackList.PlayingTrack = a.currentTrackIdx
+// playTrack starts a catalog track and moves the list highlights to it.
+func (a *App) playTrack(albumIdx, trackIdx int) tea.Cmd {
+	a.endListen(false)
+	a.resumeStarting(albumIdx, trackIdx)
+	a.selectPlaying(albumIdx, trackIdx)
 
+	track := &a.albumList.Albums[albumIdx].Tracks[trackIdx]
 	a.controls.State = panels.StateBuffering
 	a.controls.TrackTitle = track.Title
 	a.controls.AlbumColor = a.trackList.Color
 	a.controls.Position = 0
 	a.controls.Duration = 0
+	a.controls.LoopA, a.controls.LoopB = -1, -1
+	a.controls.Attempt = 0
+	a.controls.Downloaded, a.controls.DownloadNeeded, a.controls.DownloadRate = 0, 0, 0
 
 	url := track.URL
 	title := track.Title
@@ -419,71 +599,223 @@ func (a *App) playSelectedTrack() tea.Cmd {
 	}
 }
 
-func (a *App) playNext() tea.Cmd {
+// selectPlaying marks a track as current and points the album and track
+// lists at it.
+func (a *App) selectPlaying(albumIdx, trackIdx int) {
+	a.currentAlbumIdx = albumIdx
+	a.currentTrackIdx = trackIdx
+	a.hasUpcoming = false
+	a.reveal(albumIdx)
+	if a.albumList.Cursor != albumIdx {
+		a.albumList.Select(albumIdx)
+		a.syncTracks()
+	}
+	a.trackList.Select(trackIdx)
+	a.trackList.PlayingTrack = trackIdx
+	a.shufflePlayed(a.albumList.Albums[albumIdx].Tracks[trackIdx].URL)
+}
+
+// nextIndex decides which track follows the current one: the front of the
+// queue if anything is queued, else the same track when repeating one, the
+// next from the shuffle bag on shuffle, otherwise the next in album order
+// (see albumRange), going round the album or every album when repeating
+// those. ok is false when playback has run out.
+func (a *App) nextIndex() (albumIdx, trackIdx int, ok bool) {
+	if e, ok := a.queue.Peek(); ok {
+		return e.Album, e.Track, true
+	}
 	if a.currentAlbumIdx < 0 {
-		return nil
+		return 0, 0, false
 	}
 
-	// Repeat: replay same track
-	if a.controls.Repeat {
-		track := &a.albumList.Albums[a.currentAlbumIdx].Tracks[a.currentTrackIdx]
-		a.controls.State = panels.StateBuffering
-		a.controls.TrackTitle = track.Title
-		a.controls.AlbumColor = a.trackList.Color
-		a.controls.Position = 0
+	// Repeat one: replay same track, unless it was removed from its album
+	if a.controls.Repeat == panels.RepeatOne && a.currentTrackIdx >= 0 {
+		return a.currentAlbumIdx, a.currentTrackIdx, true
+	}
 
-		url := track.URL
-		title := track.Title
-		return func() tea.Msg {
-			a.player.PlayURL(url, title)
-			return player.BufferingMsg{TrackTitle: title}
+	if a.controls.Shuffle {
+		return a.shuffleNext()
+	}
+
+	albumIdx, trackIdx = a.currentAlbumIdx, a.currentTrackIdx+1
+	if n := len(a.albumList.Albums[albumIdx].Tracks); a.controls.Repeat == panels.RepeatAlbum && n > 0 {
+		return albumIdx, trackIdx % n, true
+	}
+	lo, hi := a.albumRange(albumIdx)
+	wrapped := false
+	for trackIdx >= len(a.albumList.Albums[albumIdx].Tracks) || !a.albumList.Shows(albumIdx) {
+		albumIdx++
+		if albumIdx >= hi {
+			// Repeat all goes round once more from the top
+			if a.controls.Repeat != panels.RepeatAll || wrapped {
+				return 0, 0, false
+			}
+			albumIdx, wrapped = lo, true
 		}
+		trackIdx = 0
 	}
+	return albumIdx, trackIdx, true
+}
 
-	var albumIdx, trackIdx int
+// albumRange returns the albums, lo to hi-1, that album order steps
+// through from albumIdx. Favorites and the playlists copy tracks from the
+// albums, so each plays to its own end; the rest play on into one another.
+func (a *App) albumRange(albumIdx int) (lo, hi int) {
+	if albumIdx == favoritesAlbum || albumIdx >= a.playlistBase() {
+		return albumIdx, albumIdx + 1
+	}
+	return favoritesAlbum + 1, a.playlistBase()
+}
 
-	if a.controls.Shuffle {
-		// Pick random album and track
-		albumIdx = rand.Intn(len(a.albumList.Albums))
-		trackIdx = rand.Intn(len(a.albumList.Albums[albumIdx].Tracks))
-	} else {
-		album := &a.albumList.Albums[a.currentAlbumIdx]
-		trackIdx = a.currentTrackIdx + 1
-		albumIdx = a.currentAlbumIdx
-
-		if trackIdx >= len(album.Tracks) {
-			albumIdx++
-			if albumIdx >= len(a.albumList.Albums) {
-				a.controls.State = panels.StateStopped
-				a.controls.TrackTitle = ""
-				return nil
-			}
-			trackIdx = 0
+// cycleGenre steps the album list's genre filter through every catalog
+// genre and back to all albums. Shuffle and album order keep to the
+// filtered albums too.
+func (a *App) cycleGenre() {
+	genres := append([]string{""}, data.Genres()...)
+	i := slices.Index(genres, a.albumList.Genre)
+	a.setGenre(genres[(i+1)%len(genres)])
+	a.queueNext()
+}
+
+// setGenre filters the album list to genre, showing the selected album's
+// tracks if the filter moved the selection.
+func (a *App) setGenre(genre string) {
+	cur := a.albumList.Cursor
+	a.albumList.SetGenre(genre)
+	if a.albumList.Cursor != cur {
+		a.syncTracks()
+	}
+}
+
+// reveal clears the genre filter if it hides album i.
+func (a *App) reveal(i int) {
+	if !a.albumList.Shows(i) {
+		a.setGenre("")
+	}
+}
+
+// cycleCrossfade steps the crossfade through off, 2s, 4s ... 12s and saves it.
+func (a *App) cycleCrossfade() {
+	next := (a.settings.Crossfade/2 + 1) * 2
+	if next > int(player.MaxCrossfade/time.Second) {
+		next = 0
+	}
+	a.settings.Crossfade = next
+	a.player.SetCrossfade(time.Duration(next) * time.Second)
+	if err := config.Save(a.settings); err != nil {
+		log.Printf("save settings: %v", err)
+	}
+}
+
+// cycleLoop marks loop point A at the play head, then B, which starts the
+// A–B loop, then clears both.
+func (a *App) cycleLoop() {
+	if a.controls.State != panels.StatePlaying && a.controls.State != panels.StatePaused {
+		return
+	}
+	pos := a.player.Position()
+	switch {
+	case a.controls.LoopA < 0:
+		a.controls.LoopA = pos
+	case a.controls.LoopB < 0:
+		start, end := min(a.controls.LoopA, pos), max(a.controls.LoopA, pos)
+		if end-start < player.MinLoop {
+			return
 		}
+		a.player.SetLoop(start, end)
+		a.controls.LoopA, a.controls.LoopB = start, end
+	default:
+		a.player.ClearLoop()
+		a.controls.LoopA, a.controls.LoopB = -1, -1
 	}
+}
 
-	a.currentAlbumIdx = albumIdx
-	a.albumList.Cursor = albumIdx
-	a.syncTracks()
-	a.currentTrackIdx = trackIdx
-	a.trackList.Cursor = trackIdx
-	a.trackList.PlayingTrack = trackIdx
+// speedStep is how much [ and ] change the playback rate.
+const speedStep = 0.1
 
-	track := &a.albumList.Albums[albumIdx].Tracks[trackIdx]
-	a.controls.State = panels.StateBuffering
-	a.controls.TrackTitle = track.Title
-	a.controls.AlbumColor = a.trackList.Color
-	a.controls.Position = 0
+// newControls builds the controls panel with the saved settings shown.
+func newControls(settings config.Config) panels.Controls {
+	c := panels.NewControls()
+	c.Speed = 1
+	c.Tape = settings.SpeedMode == string(player.SpeedTape)
+	return c
+}
 
-	url := track.URL
-	title := track.Title
+// changeSpeed nudges the playback rate by delta.
+func (a *App) changeSpeed(delta float64) {
+	a.player.SetSpeed(a.player.Speed() + delta)
+	a.controls.Speed = a.player.Speed()
+}
 
-	return func() tea.Msg {
-		a.player.PlayURL(url, title)
-		return player.BufferingMsg{TrackTitle: title}
+// toggleSpeedMode switches between keeping the pitch and letting it follow
+// the speed, and saves the choice.
+func (a *App) toggleSpeedMode() {
+	mode := player.SpeedTape
+	if a.settings.SpeedMode == string(player.SpeedTape) {
+		mode = player.SpeedStretch
+	}
+	a.settings.SpeedMode = string(mode)
+	a.player.SetSpeedMode(mode)
+	a.controls.Tape = mode == player.SpeedTape
+	if err := config.Save(a.settings); err != nil {
+		log.Printf("save settings: %v", err)
+	}
+}
+
+// cycleVisualizer switches the spectrum bars between off, in place of the
+// video and over it, and saves the choice.
+func (a *App) cycleVisualizer() {
+	switch a.settings.Visualizer {
+	case "off":
+		a.settings.Visualizer = "replace"
+	case "replace":
+		a.settings.Visualizer = "overlay"
+	default:
+		a.settings.Visualizer = "off"
+	}
+	if err := config.Save(a.settings); err != nil {
+		log.Printf("save settings: %v", err)
 	}
 }
 
+// queueNext settles on the upcoming track and hands it to the player to
+// prefetch, so it can start without a gap when the current one ends.
+func (a *App) queueNext() {
+	defer a.syncQueue()
+	if a.currentAlbumIdx < 0 {
+		return
+	}
+	a.upcomingAlbumIdx, a.upcomingTrackIdx, a.hasUpcoming = a.nextIndex()
+	if a.sleep.album && a.lastOfAlbum() {
+		a.hasUpcoming = false // the sleep timer stops here
+	}
+	if !a.hasUpcoming {
+		a.player.SetNext("", "")
+		return
+	}
+	track := &a.albumList.Albums[a.upcomingAlbumIdx].Tracks[a.upcomingTrackIdx]
+	a.player.SetNext(track.URL, track.Title)
+}
+
+func (a *App) playNext() tea.Cmd {
+	if a.currentAlbumIdx < 0 && a.queue.Len() == 0 {
+		return nil
+	}
+
+	// Stick with the track already queued (and maybe prefetched) if any
+	albumIdx, trackIdx, ok := a.upcomingAlbumIdx, a.upcomingTrackIdx, a.hasUpcoming
+	if !ok {
+		albumIdx, trackIdx, ok = a.nextIndex()
+	}
+	if !ok {
+		a.controls.State = panels.StateStopped
+		a.controls.TrackTitle = ""
+		return nil
+	}
+	a.takeQueued(albumIdx, trackIdx)
+	return a.playTrack(albumIdx, trackIdx)
+}
+
 func (a *App) playPrev() tea.Cmd {
 	if a.currentAlbumIdx < 0 {
 		return nil
@@ -495,36 +827,35 @@ func (a *App) playPrev() tea.Cmd {
 		return nil
 	}
 
-	prevTrack := a.currentTrackIdx - 1
-	if prevTrack < 0 {
-		// Go to previous album
-		prevAlbum := a.currentAlbumIdx - 1
-		if prevAlbum < 0 {
-			prevAlbum = len(a.albumList.Albums) - 1
-		}
-		a.currentAlbumIdx = prevAlbum
-		a.albumList.Cursor = prevAlbum
-		a.syncTracks()
-		prevTrack = len(a.albumList.Albums[prevAlbum].Tracks) - 1
+	albumIdx, trackIdx, ok := a.prevIndex()
+	if !ok {
+		return nil
 	}
+	return a.playTrack(albumIdx, trackIdx)
+}
 
-	a.currentTrackIdx = prevTrack
-	a.trackList.Cursor = prevTrack
-	a.trackList.PlayingTrack = prevTrack
-
-	track := &a.albumList.Albums[a.currentAlbumIdx].Tracks[prevTrack]
-	a.controls.State = panels.StateBuffering
-	a.controls.TrackTitle = track.Title
-	a.controls.AlbumColor = a.trackList.Color
-	a.controls.Position = 0
-
-	url := track.URL
-	title := track.Title
-
-	return func() tea.Msg {
-		a.player.PlayURL(url, title)
-		return player.BufferingMsg{TrackTitle: title}
+// prevIndex returns the track before the current one in album order (see
+// albumRange), going round from the first album to the last, or from the
+// album's first track to its last when repeating the album.
+func (a *App) prevIndex() (albumIdx, trackIdx int, ok bool) {
+	albumIdx, trackIdx = a.currentAlbumIdx, a.currentTrackIdx-1
+	// Repeat album goes round to its last track
+	if n := len(a.albumList.Albums[albumIdx].Tracks); a.controls.Repeat == panels.RepeatAlbum && trackIdx < 0 && n > 0 {
+		trackIdx = n - 1
+	}
+	lo, hi := a.albumRange(albumIdx)
+	for tries := 0; trackIdx < 0 || !a.albumList.Shows(albumIdx); tries++ {
+		if tries > hi-lo {
+			return 0, 0, false
+		}
+		// Go to previous album, passing over empty and filtered out ones
+		albumIdx--
+		if albumIdx < lo {
+			albumIdx = hi - 1
+		}
+		trackIdx = len(a.albumList.Albums[albumIdx].Tracks) - 1
 	}
+	return albumIdx, trackIdx, true
 }
 
 func (a *App) togglePause() {
@@ -539,12 +870,66 @@ func (a *App) togglePause() {
 	}
 }
 
+// newEQPanel builds the equalizer overlay from the saved settings.
+func newEQPanel(settings config.Config) panels.EQ {
+	labels := make([]string, len(player.EQBands))
+	for i, f := range player.EQBands {
+		if f >= 1000 {
+			labels[i] = fmt.Sprintf("%.0fk", f/1000)
+		} else {
+			labels[i] = fmt.Sprintf("%.0f", f)
+		}
+	}
+	return panels.NewEQ(labels, settings.EQ, settings.EQPreset, player.MaxEQGain)
+}
+
+func (a *App) handleEQKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return a, a.quit()
+	case "e", "esc":
+		a.showEQ = false
+		return a, nil
+	case "left", "h":
+		a.eq.Left()
+		return a, nil
+	case "right", "l":
+		a.eq.Right()
+		return a, nil
+	case "up", "k":
+		a.eq.Adjust(1)
+	case "down", "j":
+		a.eq.Adjust(-1)
+	case "0":
+		a.eq.Adjust(-a.eq.Gains[a.eq.Band])
+	case "p":
+		next := 0
+		for i, preset := range player.EQPresets {
+			if preset.Name == a.eq.Preset {
+				next = (i + 1) % len(player.EQPresets)
+			}
+		}
+		a.eq.SetPreset(player.EQPresets[next].Name, player.EQPresets[next].Gains)
+	case " ":
+		a.togglePause()
+		return a, nil
+	default:
+		return a, nil
+	}
+
+	a.player.SetEQ(a.eq.Gains)
+	a.settings.EQ = append([]float64(nil), a.eq.Gains...)
+	a.settings.EQPreset = a.eq.Preset
+	if err := config.Save(a.settings); err != nil {
+		log.Printf("save settings: %v", err)
+	}
+	return a, nil
+}
+
 func (a *App) handleChatKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c":
-		a.player.Close()
-		a.chatClient.Stop()
-		return a, tea.Quit
+		return a, a.quit()
 	case "esc", "tab":
 		a.switchFocus()
 	case "ctrl+u":
@@ -588,6 +973,14 @@ func (a *App) sendChatMsg(text string) tea.Cmd {
 		}
 		return nil
 	}
+	if verb, _, _ := strings.Cut(text, " "); verb == "/pl" || verb == "/playlist" {
+		a.playlistCommand(strings.TrimPrefix(strings.TrimPrefix(text, verb), " "))
+		return nil
+	}
+	if verb, arg, _ := strings.Cut(text, " "); verb == "/sleep" {
+		a.sleepCommand(arg)
+		return nil
+	}
 	if text == "/reset" {
 		a.nickname = chat.GenerateAnonName()
 		chat.SaveConfig(chat.Config{Nickname: a.nickname})
@@ -619,6 +1012,14 @@ func (a *App) switchFocus() {
 	}
 }
 
+// setFocus moves the focus straight to f.
+func (a *App) setFocus(f focus) {
+	a.focus = f
+	a.albumList.Focused = f == focusAlbums
+	a.trackList.Focused = f == focusTracks
+	a.chat.Focused = f == focusChat
+}
+
 func (a *App) navigateUp() {
 	switch a.focus {
 	case focusAlbums:
@@ -688,28 +1089,67 @@ func (a *App) renderHelpBar() string {
 			br, ky, name, br, label)
 	}
 
+	// The genre hint stays on screen while a filter is on
+	genreStr, genreDrop := key("C", fmt.Sprintf("\x1b[38;5;%smGENRE", lb)), 1
+	if g := a.albumList.Genre; g != "" {
+		genreStr, genreDrop = key("C", fmt.Sprintf("\x1b[38;5;%smGENRE \x1b[38;5;231m%s", lb, g)), 0
+	}
+
 	var shuffleStr, repeatStr string
 	if a.controls.Shuffle {
-		shuffleStr = fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%smS\x1b[38;5;%sm] \x1b[38;5;%smSHUFFLE\x1b[38;5;231m+\x1b[0m", br, ky, br, lb)
+		scope := ""
+		if a.settings.Shuffle != "all" {
+			scope = " " + strings.ToUpper(a.settings.Shuffle)
+		}
+		shuffleStr = fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%smS\x1b[38;5;%sm] \x1b[38;5;%smSHUFFLE\x1b[38;5;231m+%s\x1b[0m", br, ky, br, lb, scope)
 	} else {
 		shuffleStr = fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%smS\x1b[38;5;%sm] \x1b[38;5;%smSHUFFLE\x1b[0m", br, ky, br, lb)
 	}
-	if a.controls.Repeat {
-		repeatStr = fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%smR\x1b[38;5;%sm] \x1b[38;5;%smREPEAT\x1b[38;5;231m+\x1b[0m", br, ky, br, lb)
+	mode := strings.ToUpper(a.controls.Repeat.String())
+	if a.controls.Repeat != panels.RepeatOff {
+		repeatStr = fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%smR\x1b[38;5;%sm] \x1b[38;5;%smREPEAT \x1b[38;5;231m%s\x1b[0m", br, ky, br, lb, mode)
 	} else {
-		repeatStr = fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%smR\x1b[38;5;%sm] \x1b[38;5;%smREPEAT\x1b[0m", br, ky, br, lb)
+		repeatStr = fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%smR\x1b[38;5;%sm] \x1b[38;5;%smREPEAT %s\x1b[0m", br, ky, br, lb, mode)
 	}
 
 	verStr := fmt.Sprintf("\x1b[38;5;%smv%s\x1b[0m", t.FadeColor, a.version)
-	left := " " + strings.Join([]string{
-		verStr,
-		key("TAB", fmt.Sprintf("\x1b[38;5;%smSWITCH", lb)),
-		key("ENTER", fmt.Sprintf("\x1b[38;5;%smPLAY", lb)),
-		key("SPACE", a.pauseLabel()),
-		fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%sm←\x1b[38;5;%sm/\x1b[38;5;%sm→\x1b[38;5;%sm] \x1b[38;5;%smSEEK\x1b[0m", br, ky, br, ky, br, lb),
-		shuffleStr,
-		repeatStr,
-	}, "  ")
+	eqStr := key("E", fmt.Sprintf("\x1b[38;5;%smEQ", lb))
+	if a.eq.Preset != "Flat" {
+		eqStr = key("E", fmt.Sprintf("\x1b[38;5;%smEQ\x1b[38;5;231m+", lb))
+	}
+
+	visStr := key("V", fmt.Sprintf("\x1b[38;5;%smVIS", lb))
+	if a.settings.Visualizer != "off" {
+		visStr = key("V", fmt.Sprintf("\x1b[38;5;%smVIS\x1b[38;5;231m+", lb))
+	}
+
+	// drop orders the hints to leave out when the bar runs out of room,
+	// most self-explanatory first; 0 is always shown.
+	type hint struct {
+		text string
+		drop int
+	}
+	hints := []hint{
+		{verStr, 0},
+		{key("TAB", fmt.Sprintf("\x1b[38;5;%smSWITCH", lb)), 1},
+		{key("ENTER", fmt.Sprintf("\x1b[38;5;%smPLAY", lb)), 2},
+		{key("SPACE", a.pauseLabel()), 0},
+		{fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%sm←\x1b[38;5;%sm/\x1b[38;5;%sm→\x1b[38;5;%sm] \x1b[38;5;%smSEEK\x1b[0m", br, ky, br, ky, br, lb), 4},
+		{shuffleStr, 0},
+		{genreStr, genreDrop},
+		{repeatStr, 0},
+		{eqStr, 3},
+		{visStr, 1},
+		{key("F", fmt.Sprintf("\x1b[38;5;%smFAV", lb)), 1},
+		{key("H", fmt.Sprintf("\x1b[38;5;%smSTATS", lb)), 1},
+		{key("/", fmt.Sprintf("\x1b[38;5;%smSEARCH", lb)), 2},
+	}
+	if n := a.queue.Len(); n > 0 {
+		hints = append(hints, hint{key("U", fmt.Sprintf("\x1b[38;5;%smQUEUE \x1b[38;5;231m%d", lb, n)), 0})
+	}
+	if a.settings.Crossfade > 0 {
+		hints = append(hints, hint{key("X", fmt.Sprintf("\x1b[38;5;%smFADE \x1b[38;5;231m%ds", lb, a.settings.Crossfade)), 0})
+	}
 
 	volChars := []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
 	var volStr string
@@ -724,9 +1164,30 @@ func (a *App) renderHelpBar() string {
 	volKeys := fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%sm-\x1b[38;5;%sm/\x1b[38;5;%sm+\x1b[38;5;%sm]", br, ky, br, ky, br)
 	right := fmt.Sprintf("%s %s \x1b[0m%s\x1b[0m ", themeKey, volKeys, volStr)
 
-	leftVis := panels.AnsiVisLen(left)
 	rightVis := panels.AnsiVisLen(right)
-	gap := a.width - leftVis - rightVis
+	join := func() string {
+		parts := make([]string, len(hints))
+		for i, h := range hints {
+			parts[i] = h.text
+		}
+		return " " + strings.Join(parts, "  ")
+	}
+	left := join()
+	for panels.AnsiVisLen(left)+rightVis >= a.width {
+		drop := -1
+		for i, h := range hints {
+			if h.drop > 0 && (drop < 0 || h.drop < hints[drop].drop) {
+				drop = i
+			}
+		}
+		if drop < 0 {
+			break
+		}
+		hints = append(hints[:drop], hints[drop+1:]...)
+		left = join()
+	}
+
+	gap := a.width - panels.AnsiVisLen(left) - rightVis
 	if gap < 1 {
 		gap = 1
 	}
@@ -824,7 +1285,14 @@ func (a *App) View() string {
 		return ""
 	}
 
-	leftCol := a.video.View() + "\n" + a.chat.View()
+	videoStr := a.video.View()
+	switch a.settings.Visualizer {
+	case "replace":
+		videoStr = a.visualizer.View()
+	case "overlay":
+		videoStr = a.visualizer.Over(videoStr)
+	}
+	leftCol := videoStr + "\n" + a.chat.View()
 	rightCol := a.albumList.View() + "\n" + a.trackList.View()
 
 	topSection := joinHorizontal(leftCol, rightCol, a.video.FrameWidth())
@@ -832,7 +1300,32 @@ func (a *App) View() string {
 
 	helpBar := a.renderHelpBar()
 
-	return a.wrapBg(topSection + "\n" + controlsStr + "\n" + helpBar)
+	screen := topSection + "\n" + controlsStr + "\n" + helpBar
+	if a.showEQ {
+		box := a.eq.View()
+		x := (a.width - a.eq.Width()) / 2
+		y := (a.height - strings.Count(box, "\n") - 1) / 2
+		screen = panels.Overlay(screen, box, x, y)
+	}
+	if a.showQueue {
+		box := a.queuePanel.View()
+		x := (a.width - a.queuePanel.Width()) / 2
+		y := (a.height - strings.Count(box, "\n") - 1) / 2
+		screen = panels.Overlay(screen, box, x, y)
+	}
+	if a.showStats {
+		box := a.statsPanel.View()
+		x := (a.width - a.statsPanel.Width()) / 2
+		y := (a.height - strings.Count(box, "\n") - 1) / 2
+		screen = panels.Overlay(screen, box, x, y)
+	}
+	if a.showSearch {
+		box := a.searchPanel.View()
+		x := (a.width - a.searchPanel.Width()) / 2
+		y := (a.height - strings.Count(box, "\n") - 1) / 2
+		screen = panels.Overlay(screen, box, x, y)
+	}
+	return a.wrapBg(screen)
 }
 
 func joinHorizontal(left, right string, leftWidth int) string {
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/queue"
+)
+
+// favoritesAlbum is the album list index of the Favorites smart album,
+// which always heads the list.
+const favoritesAlbum = 0
+
+// favoritesTitle names the Favorites smart album.
+const favoritesTitle = "♥ Favorites"
+
+// markTarget is the track f and 1-5 act on: the highlighted one in the
+// track list, or else the playing one.
+func (a *App) markTarget() *data.Track {
+	if a.focus == focusTracks {
+		return a.trackList.SelectedTrack()
+	}
+	if a.currentAlbumIdx >= 0 && a.currentTrackIdx >= 0 {
+		return &a.albumList.Albums[a.currentAlbumIdx].Tracks[a.currentTrackIdx]
+	}
+	return nil
+}
+
+// toggleFavorite favorites or unfavorites the target track.
+func (a *App) toggleFavorite() {
+	track := a.markTarget()
+	if track == nil {
+		return
+	}
+	a.favorites.ToggleFavorite(track.URL, time.Now())
+	a.marksChanged()
+}
+
+// rate gives the target track stars; giving it the rating it already has
+// clears it.
+func (a *App) rate(stars int) {
+	track := a.markTarget()
+	if track == nil {
+		return
+	}
+	if a.favorites.Get(track.URL).Rating == stars {
+		stars = 0
+	}
+	a.favorites.Rate(track.URL, stars)
+	a.marksChanged()
+}
+
+// marksChanged saves the favorites and rebuilds the smart album, whose
+// order follows the ratings.
+func (a *App) marksChanged() {
+	if err := a.favorites.Save(); err != nil {
+		log.Printf("save favorites: %v", err)
+	}
+	a.refreshFavorites()
+}
+
+// trackMarks reports a track's favorite and rating for the track list.
+func (a *App) trackMarks(url string) (bool, int) {
+	m := a.favorites.Get(url)
+	return m.Favorite, m.Rating
+}
+
+// refreshFavorites refills the Favorites album from the store, keeping the
+// queue and the playing track pointed at the same tracks.
+func (a *App) refreshFavorites() {
+	byURL := map[string]data.Track{}
+	for _, album := range a.albumList.Albums[favoritesAlbum+1:] {
+		for _, t := range album.Tracks {
+			if _, ok := byURL[t.URL]; !ok {
+				byURL[t.URL] = t
+			}
+		}
+	}
+	var tracks []data.Track
+	for _, url := range a.favorites.Favorites() {
+		if t, ok := byURL[url]; ok {
+			tracks = append(tracks, t)
+		}
+	}
+
+	old := a.albumList.Albums[favoritesAlbum].Tracks
+	a.albumList.Albums[favoritesAlbum].Tracks = tracks
+	moved := func(i int) int {
+		return slices.IndexFunc(tracks, func(t data.Track) bool { return t.URL == old[i].URL })
+	}
+	a.queue.Remap(func(e queue.Entry) (queue.Entry, bool) {
+		if e.Album != favoritesAlbum {
+			return e, true
+		}
+		e.Track = moved(e.Track)
+		return e, e.Track >= 0
+	})
+	if a.currentAlbumIdx == favoritesAlbum {
+		if i := moved(a.currentTrackIdx); i >= 0 {
+			a.currentTrackIdx = i
+		} else {
+			// Unfavorited while playing: what followed it is next
+			a.currentTrackIdx = min(a.currentTrackIdx, len(tracks)) - 1
+		}
+		if a.albumList.Cursor == favoritesAlbum {
+			a.trackList.PlayingTrack = a.currentTrackIdx
+		}
+	}
+	a.repointTracks()
+	a.queueChanged()
+}
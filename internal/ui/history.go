@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/history"
+	"github.com/dangerous-person/dopogoto/internal/ui/panels"
+)
+
+// listen is the play under way, added to the history when it ends.
+type listen struct {
+	play  history.Play
+	since time.Time // when listening time was last added up
+}
+
+// startListen begins timing the track that has just started playing.
+func (a *App) startListen() {
+	if a.currentAlbumIdx < 0 || a.currentTrackIdx < 0 {
+		return
+	}
+	track := a.albumList.Albums[a.currentAlbumIdx].Tracks[a.currentTrackIdx]
+	now := time.Now()
+	a.listening = &listen{
+		play: history.Play{
+			URL:   track.URL,
+			Title: track.Title,
+			Album: a.albumOf(a.currentAlbumIdx, track.URL),
+			Start: now,
+		},
+		since: now,
+	}
+}
+
+// countListen adds the time played since it was last counted; paused and
+// buffering time doesn't count.
+func (a *App) countListen(now time.Time) {
+	if a.listening == nil {
+		return
+	}
+	if a.controls.State == panels.StatePlaying {
+		a.listening.play.Seconds += now.Sub(a.listening.since).Seconds()
+	}
+	a.listening.since = now
+}
+
+// endListen records the play under way, if there is one, as finished or
+// skipped.
+func (a *App) endListen(completed bool) {
+	if a.listening == nil {
+		return
+	}
+	a.countListen(time.Now())
+	p := a.listening.play
+	p.Completed = completed
+	a.listening = nil
+	if err := history.Append(history.DefaultPath(), p); err != nil {
+		log.Printf("record play: %v", err)
+	}
+}
+
+// albumOf names the album a track belongs to. Tracks played from
+// Favorites or a playlist are credited to the album they come from.
+func (a *App) albumOf(albumIdx int, url string) string {
+	albums := a.albumList.Albums
+	base := a.playlistBase()
+	if albumIdx > favoritesAlbum && albumIdx < base {
+		return albums[albumIdx].Title
+	}
+	for _, album := range albums[favoritesAlbum+1 : base] {
+		if slices.ContainsFunc(album.Tracks, func(t data.Track) bool { return t.URL == url }) {
+			return album.Title
+		}
+	}
+	return albums[albumIdx].Title
+}
+
+// openStats loads the history into the stats overlay and shows it.
+func (a *App) openStats() {
+	plays, err := history.Load(history.DefaultPath())
+	if err != nil {
+		log.Printf("load history: %v", err)
+	}
+	a.statsPanel.Summary = history.Summarize(plays, time.Now(), panels.StatsRows)
+	a.showStats = true
+}
+
+func (a *App) handleStatsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return a, a.quit()
+	case "h", "esc":
+		a.showStats = false
+	case " ":
+		a.togglePause()
+	}
+	return a, nil
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"log"
+	"os"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/library"
+	"github.com/dangerous-person/dopogoto/internal/queue"
+)
+
+// libraryMsg carries the albums found in the local music folders.
+type libraryMsg []data.Album
+
+// libraryFolders joins the library folders from the settings, which may
+// start with ~, and those from the command line.
+func libraryFolders(configured, extra []string) []string {
+	dirs := make([]string, 0, len(configured)+len(extra))
+	for _, dir := range slices.Concat(configured, extra) {
+		dirs = append(dirs, expandHome(dir))
+	}
+	return dirs
+}
+
+// scanLibrary looks through dirs for local music off the UI, as reading
+// every file's tags can take a while. Folders that can't be read are
+// logged and skipped.
+func scanLibrary(dirs []string) tea.Cmd {
+	if len(dirs) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		for _, dir := range dirs {
+			if _, err := os.Stat(dir); err != nil {
+				log.Printf("library: %v", err)
+			}
+		}
+		return libraryMsg(library.Scan(dirs))
+	}
+}
+
+// addLibrary lists the local albums after the catalog, ahead of the
+// playlists, and picks up the last session's track if it was one of them.
+func (a *App) addLibrary(albums []data.Album) {
+	if len(albums) == 0 {
+		return
+	}
+	idx := a.playlistBase()
+	a.albumList.Albums = slices.Insert(a.albumList.Albums, idx, albums...)
+	a.albumsInserted(idx, len(albums))
+	a.refreshFavorites()
+	if st := a.unresumed; st != nil && a.resume == nil && a.currentAlbumIdx < 0 {
+		a.unresumed = nil
+		a.restoreTrack(*st)
+	}
+}
+
+// albumsInserted keeps the queue, the playing track and the album list's
+// cursor in step after n albums are put in the list at idx.
+func (a *App) albumsInserted(idx, n int) {
+	shift := func(i int) int {
+		if i >= idx {
+			return i + n
+		}
+		return i
+	}
+	a.queue.Remap(func(e queue.Entry) (queue.Entry, bool) {
+		e.Album = shift(e.Album)
+		return e, true
+	})
+	if a.currentAlbumIdx >= 0 {
+		a.currentAlbumIdx = shift(a.currentAlbumIdx)
+	}
+	if a.resume != nil {
+		a.resume.albumIdx = shift(a.resume.albumIdx)
+	}
+	cursor := a.trackList.Cursor
+	a.albumList.Select(shift(a.albumList.Cursor))
+	a.syncTracks()
+	a.trackList.Select(cursor)
+	a.queueChanged()
+}
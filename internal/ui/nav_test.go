@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/playlist"
+	"github.com/dangerous-person/dopogoto/internal/ui/panels"
+)
+
+// navApp lists Favorites, albums A, an empty B and C, then one playlist,
+// each but B with two tracks.
+func navApp() *App {
+	album := func(title string, n int) data.Album {
+		a := data.Album{Title: title}
+		for i := range n {
+			a.Tracks = append(a.Tracks, data.Track{Title: fmt.Sprint(i), URL: fmt.Sprintf("https://cdn/%s/%d.mp3", title, i)})
+		}
+		return a
+	}
+	albums := []data.Album{album(favoritesTitle, 2), album("A", 2), album("B", 0), album("C", 2), album("Mix", 2)}
+	return &App{
+		albumList:       panels.NewAlbumList(albums),
+		playlists:       []playlist.Playlist{{Name: "Mix"}},
+		currentAlbumIdx: -1,
+		currentTrackIdx: -1,
+	}
+}
+
+func TestNextIndex(t *testing.T) {
+	const end = -1
+	tests := []struct {
+		name         string
+		repeat       panels.RepeatMode
+		album, track int
+		wantAlbum    int // end when playback stops
+		wantTrack    int
+	}{
+		{"next track", panels.RepeatOff, 1, 0, 1, 1},
+		{"next album, passing the empty one", panels.RepeatOff, 1, 1, 3, 0},
+		{"stops after the last album", panels.RepeatOff, 3, 1, end, 0},
+		{"repeat one", panels.RepeatOne, 3, 1, 3, 1},
+		{"repeat album goes round", panels.RepeatAlbum, 3, 1, 3, 0},
+		{"repeat album stays in the album", panels.RepeatAlbum, 1, 1, 1, 0},
+		{"repeat all wraps to the first album", panels.RepeatAll, 3, 1, 1, 0},
+		{"favorites plays to its own end", panels.RepeatOff, 0, 1, end, 0},
+		{"playlist plays to its own end", panels.RepeatOff, 4, 1, end, 0},
+		{"repeat all keeps to the playlist", panels.RepeatAll, 4, 1, 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := navApp()
+			a.controls.Repeat = tt.repeat
+			a.currentAlbumIdx, a.currentTrackIdx = tt.album, tt.track
+			album, track, ok := a.nextIndex()
+			if tt.wantAlbum == end {
+				if ok {
+					t.Errorf("after %d/%d got %d/%d, want playback to stop", tt.album, tt.track, album, track)
+				}
+				return
+			}
+			if !ok || album != tt.wantAlbum || track != tt.wantTrack {
+				t.Errorf("after %d/%d got %d/%d (ok %v), want %d/%d", tt.album, tt.track, album, track, ok, tt.wantAlbum, tt.wantTrack)
+			}
+		})
+	}
+}
+
+func TestPrevIndex(t *testing.T) {
+	tests := []struct {
+		name                 string
+		repeat               panels.RepeatMode
+		album, track         int
+		wantAlbum, wantTrack int
+	}{
+		{"previous track", panels.RepeatOff, 3, 1, 3, 0},
+		{"previous album, passing the empty one", panels.RepeatOff, 3, 0, 1, 1},
+		{"first album goes round to the last, not Favorites", panels.RepeatOff, 1, 0, 3, 1},
+		{"repeat album goes round to its last track", panels.RepeatAlbum, 1, 0, 1, 1},
+		{"playlist keeps to itself", panels.RepeatOff, 4, 0, 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := navApp()
+			a.controls.Repeat = tt.repeat
+			a.currentAlbumIdx, a.currentTrackIdx = tt.album, tt.track
+			album, track, ok := a.prevIndex()
+			if !ok || album != tt.wantAlbum || track != tt.wantTrack {
+				t.Errorf("before %d/%d got %d/%d (ok %v), want %d/%d", tt.album, tt.track, album, track, ok, tt.wantAlbum, tt.wantTrack)
+			}
+		})
+	}
+}
@@ -9,11 +9,12 @@ import (
 
 type AlbumList struct {
 	Albums  []data.Album
-	Cursor  int
-	Offset  int
+	Cursor  int // index into Albums
+	Offset  int // first row shown, counting only the albums the filter shows
 	Width   int
 	Height  int
 	Focused bool
+	Genre   string // only albums of this genre are listed; "" lists all
 }
 
 func NewAlbumList(albums []data.Album) AlbumList {
@@ -25,41 +26,84 @@ func NewAlbumList(albums []data.Album) AlbumList {
 }
 
 func (a *AlbumList) Up() {
-	if a.Cursor > 0 {
-		a.Cursor--
-		if a.Cursor < a.Offset {
-			a.Offset = a.Cursor
+	for i := a.Cursor - 1; i >= 0; i-- {
+		if a.Shows(i) {
+			a.Select(i)
+			return
 		}
 	}
 }
 
 func (a *AlbumList) Down() {
-	if a.Cursor < len(a.Albums)-1 {
-		a.Cursor++
-		vis := a.visibleAlbums()
-		if a.Cursor >= a.Offset+vis {
-			a.Offset = a.Cursor - vis + 1
+	for i := a.Cursor + 1; i < len(a.Albums); i++ {
+		if a.Shows(i) {
+			a.Select(i)
+			return
 		}
 	}
 }
 
 func (a *AlbumList) Top() {
-	a.Cursor = 0
-	a.Offset = 0
+	if shown := a.shown(); len(shown) > 0 {
+		a.Select(shown[0])
+	}
 }
 
 func (a *AlbumList) Bottom() {
-	if len(a.Albums) == 0 {
+	if shown := a.shown(); len(shown) > 0 {
+		a.Select(shown[len(shown)-1])
+	}
+}
+
+// Select moves the cursor to album i, scrolling it into view. Albums the
+// genre filter hides can't be selected.
+func (a *AlbumList) Select(i int) {
+	if i < 0 || i >= len(a.Albums) || !a.Shows(i) {
 		return
 	}
-	a.Cursor = len(a.Albums) - 1
+	a.Cursor = i
+	row := 0
+	for j := range i {
+		if a.Shows(j) {
+			row++
+		}
+	}
 	vis := a.visibleAlbums()
-	a.Offset = a.Cursor - vis + 1
-	if a.Offset < 0 {
-		a.Offset = 0
+	if row < a.Offset {
+		a.Offset = row
+	} else if row >= a.Offset+vis {
+		a.Offset = row - vis + 1
 	}
 }
 
+// Shows reports whether the genre filter lets album i be listed.
+func (a *AlbumList) Shows(i int) bool {
+	return a.Genre == "" || a.Albums[i].Genre == a.Genre
+}
+
+// shown returns the indexes of the albums the genre filter lists.
+func (a *AlbumList) shown() []int {
+	var shown []int
+	for i := range a.Albums {
+		if a.Shows(i) {
+			shown = append(shown, i)
+		}
+	}
+	return shown
+}
+
+// SetGenre lists only albums of genre, or every album for "". If the
+// selected album is filtered out, the first one left is selected.
+func (a *AlbumList) SetGenre(genre string) {
+	a.Genre = genre
+	a.Offset = 0
+	if a.Cursor < len(a.Albums) && a.Shows(a.Cursor) {
+		a.Select(a.Cursor)
+		return
+	}
+	a.Top()
+}
+
 // visibleAlbums returns how many albums fit in the panel.
 func (a *AlbumList) visibleAlbums() int {
 	n := a.Height - 2 // border (2)
@@ -108,11 +152,15 @@ func (a AlbumList) View() string {
 
 	var b strings.Builder
 
-	// Top border with title: ╭─ Albums ──...──╮
+	// Top border with title: ╭─ Albums ──...──╮, or the genre filtered on
 	// 1st letter white, 2nd light yellow, rest yellow
-	titleAnsi := BuildTitleGradient("Albums", t.TitleGrad1, t.TitleGrad2, t.TitleGrad3)
+	name := "Albums"
+	if a.Genre != "" {
+		name = data.GenreName(a.Genre)
+	}
+	titleAnsi := BuildTitleGradient(name, t.TitleGrad1, t.TitleGrad2, t.TitleGrad3)
 	title := fmt.Sprintf(" %s\x1b[38;5;%sm ", titleAnsi, borderColor)
-	titleVisLen := 8 // " Albums " = 8 visible chars
+	titleVisLen := len([]rune(name)) + 2
 	remaining := contentW - titleVisLen
 	if remaining < 0 {
 		remaining = 0
@@ -136,9 +184,25 @@ func (a AlbumList) View() string {
 	contentLines := a.Height - 2 // total rows inside borders
 
 	lineIdx := 0
-	for i := a.Offset; i < len(a.Albums) && i < a.Offset+vis; i++ {
+	shown := a.shown()
+	for row := a.Offset; row < len(shown) && row < a.Offset+vis; row++ {
+		i := shown[row]
 		album := a.Albums[i]
-		title := truncate(album.Title, contentW-3) // 2 prefix + 1 margin
+
+		// Catalog albums are tagged with their genre code on the right
+		tag := ""
+		if data.IsGenre(album.Genre) {
+			tag = album.Genre
+		}
+		maxTitle := contentW - 3 // 2 prefix + 1 margin
+		if tag != "" {
+			maxTitle -= len(tag) + 1
+		}
+		title := truncate(album.Title, maxTitle)
+		gap := ""
+		if tag != "" {
+			gap = strings.Repeat(" ", maxTitle-len([]rune(title))+1)
+		}
 
 		if i == a.Cursor {
 			// Active album: text on selection bg, full row
@@ -146,7 +210,7 @@ func (a AlbumList) View() string {
 			if selFg == "" {
 				selFg = "231"
 			}
-			visText := "  " + title
+			visText := "  " + title + gap + tag
 			pad := contentW - len([]rune(visText))
 			if pad < 0 {
 				pad = 0
@@ -154,7 +218,7 @@ func (a AlbumList) View() string {
 			titleLine := fmt.Sprintf("\x1b[48;5;%sm\x1b[38;5;%sm%s%s\x1b[0m", t.SelectionBg, selFg, visText, strings.Repeat(" ", pad))
 			writeBorderedLine(&b, borderColor, fadeColor, titleLine, contentW, lineIdx, contentLines, false)
 		} else {
-			titleLine := fmt.Sprintf("  \x1b[38;5;%sm%s\x1b[0m", t.TextColor, title)
+			titleLine := fmt.Sprintf("  \x1b[38;5;%sm%s%s\x1b[38;5;%sm%s\x1b[0m", t.TextColor, title, gap, t.TextDim, tag)
 			writeBorderedLine(&b, borderColor, fadeColor, titleLine, contentW, lineIdx, contentLines, false)
 		}
 		lineIdx++
@@ -17,6 +17,37 @@ const (
 	StatePaused
 )
 
+// RepeatMode is what plays again when playback runs out.
+type RepeatMode int
+
+const (
+	RepeatOff   RepeatMode = iota
+	RepeatOne              // the playing track, over and over
+	RepeatAlbum            // the playing album, from its first track after its last
+	RepeatAll              // every album, from the first after the last
+)
+
+var repeatNames = [...]string{"off", "one", "album", "all"}
+
+func (m RepeatMode) String() string {
+	return repeatNames[m]
+}
+
+// Next returns the mode after m, from all back round to off.
+func (m RepeatMode) Next() RepeatMode {
+	return (m + 1) % RepeatMode(len(repeatNames))
+}
+
+// ParseRepeat returns the mode called name, or RepeatOff if there's none.
+func ParseRepeat(name string) RepeatMode {
+	for i, n := range repeatNames {
+		if n == name {
+			return RepeatMode(i)
+		}
+	}
+	return RepeatOff
+}
+
 type Controls struct {
 	State      PlayState
 	TrackTitle string
@@ -26,14 +57,30 @@ type Controls struct {
 	Width      int
 	Height     int
 	Shuffle    bool
-	Repeat     bool
-	AlbumColor string // 256-color for played portion of timeline
+	Repeat     RepeatMode
+	AlbumColor string        // 256-color for played portion of timeline
+	SourceRate int           // track sample rate in Hz, 0 if unknown
+	OutputRate int           // output sample rate in Hz
+	Speed      float64       // playback rate, 1 (or 0) is normal
+	Tape       bool          // pitch follows the rate
+	Sleep      time.Duration // time left on the sleep timer, 0 when off or not known yet
+	SleepAlbum bool          // the sleep timer runs to the end of the album
+	LoopA      time.Duration // A–B loop start, -1 when not set
+	LoopB      time.Duration // A–B loop end, -1 when not set
+	Attempt    int           // download retry under way, 0 when none
+	Attempts   int           // retries allowed before the track is skipped
+
+	Downloaded     int64   // bytes of the buffering track downloaded so far
+	DownloadNeeded int64   // bytes it needs before playback starts, <= 0 until known
+	DownloadRate   float64 // bytes per second, 0 when stalled
 }
 
 func NewControls() Controls {
 	return Controls{
 		State:  StateStopped,
 		Volume: 7,
+		LoopA:  -1,
+		LoopB:  -1,
 	}
 }
 
@@ -80,10 +127,16 @@ func (c Controls) View() string {
 	if trackTitle == "" {
 		trackTitle = "Choose a Song"
 	}
+	if c.Attempt > 0 {
+		trackTitle += fmt.Sprintf(" · retry %d/%d", c.Attempt, c.Attempts)
+	}
 
 	posStr := data.FormatDuration(c.Position)
 	durStr := data.FormatDuration(c.Duration)
 	timer := fmt.Sprintf("[%s/%s]", posStr, durStr)
+	if c.State == StateBuffering && (c.Downloaded > 0 || c.DownloadNeeded > 0) {
+		timer = bufferLabel(c.Downloaded, c.DownloadNeeded, c.DownloadRate)
+	}
 	timerLen := len([]rune(timer))
 
 	// Title + space + timer
@@ -120,12 +173,28 @@ func (c Controls) View() string {
 		playedBg = t.SelectionBg
 	}
 
+	// A–B loop marks, drawn over the title if they land on it
+	markA, markB := c.loopColumn(c.LoopA, contentW), c.loopColumn(c.LoopB, contentW)
+	if markB >= 0 && markB == markA {
+		markB++
+	}
+
 	var line strings.Builder
 	for i := 0; i < contentW; i++ {
 		played := i < filled
 		inDisplay := i >= displayStart && i < displayStart+dLen
 
-		if inDisplay {
+		bg := "\x1b[49m"
+		if played {
+			bg = fmt.Sprintf("\x1b[48;5;%sm", playedBg)
+		}
+		if i == markA || i == markB {
+			mark := '['
+			if i == markB {
+				mark = ']'
+			}
+			line.WriteString(fmt.Sprintf("%s\x1b[38;5;%sm%c", bg, t.ChatNameColor, mark))
+		} else if inDisplay {
 			ch := displayRunes[i-displayStart]
 			isTimer := (i - displayStart) >= timerStart
 			var fg string
@@ -142,20 +211,118 @@ func (c Controls) View() string {
 				line.WriteString(fmt.Sprintf("\x1b[49m\x1b[38;5;%sm%c", fg, ch))
 			}
 		} else {
-			if played {
-				line.WriteString(fmt.Sprintf("\x1b[48;5;%sm ", playedBg))
-			} else {
-				line.WriteString("\x1b[49m ")
-			}
+			line.WriteString(bg + " ")
 		}
 	}
 	line.WriteString("\x1b[0m")
 	writeBorderedLine(&b, t.BorderColor, t.FadeColor, line.String(), contentW, 0, 1, true)
 
-	// Bottom border
+	// Bottom border, with the sleep timer at the left and the speed and
+	// sample rate tucked in at the right
 	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╰", t.CornerColor))
-	b.WriteString(FadeBorder(contentW+2, FadeDashes, t.FadeColor, t.BorderColor))
+	borderW := contentW + 2
+	if sleep := sleepLabel(c.Sleep, c.SleepAlbum); sleep != "" {
+		b.WriteString(fmt.Sprintf("\x1b[38;5;%sm──\x1b[38;5;%sm %s ", t.FadeColor, t.TextDim, sleep))
+		borderW -= len([]rune(sleep)) + 4
+	}
+	rate := rateLabel(c.SourceRate, c.OutputRate)
+	if speed := speedLabel(c.Speed, c.Tape); speed != "" && rate != "" {
+		rate = speed + " · " + rate
+	} else if speed != "" {
+		rate = speed
+	}
+	if rate != "" && contentW > 40 {
+		rateLen := len([]rune(rate)) + 4
+		b.WriteString(FadeBorder(borderW-rateLen, FadeDashes, t.FadeColor, t.BorderColor))
+		b.WriteString(fmt.Sprintf("\x1b[38;5;%sm %s ", t.TextDim, rate))
+		b.WriteString(fmt.Sprintf("\x1b[38;5;%sm──", t.FadeColor))
+	} else {
+		b.WriteString(FadeBorder(borderW, FadeDashes, t.FadeColor, t.BorderColor))
+	}
 	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╯\x1b[0m", t.CornerColor))
 
 	return b.String()
 }
+
+// loopColumn returns the timeline column of loop point d, or -1 if it's
+// not set.
+func (c Controls) loopColumn(d time.Duration, contentW int) int {
+	if d < 0 || c.Duration <= 0 {
+		return -1
+	}
+	return min(contentW-1, int(float64(contentW)*float64(d)/float64(c.Duration)))
+}
+
+// sleepLabel formats the sleep timer, e.g. "sleep 29:45", and is empty
+// when it's off.
+func sleepLabel(left time.Duration, album bool) string {
+	switch {
+	case left > 0:
+		return "sleep " + data.FormatDuration(left)
+	case album:
+		return "sleep: end of album"
+	}
+	return ""
+}
+
+// bufferBarW is the width of the download bar shown while buffering.
+const bufferBarW = 8
+
+// bufferLabel formats buffering progress in place of the timer: a bar
+// filling up to the bytes needed to start playing and the time left till
+// then, e.g. "[███░░░░░ 0:07]", or "[1.2 MB 340 KB/s]" while that isn't
+// known yet. A download that has stopped receiving data reads "stalled".
+func bufferLabel(downloaded, needed int64, rate float64) string {
+	eta := "stalled"
+	if needed <= 0 {
+		if rate > 0 {
+			eta = formatBytes(rate) + "/s"
+		}
+		return fmt.Sprintf("[%s %s]", formatBytes(float64(downloaded)), eta)
+	}
+	filled := min(bufferBarW, int(bufferBarW*downloaded/needed))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", bufferBarW-filled)
+	if rate > 0 {
+		eta = data.FormatDuration(time.Duration(float64(max(needed-downloaded, 0)) / rate * float64(time.Second)))
+	}
+	return fmt.Sprintf("[%s %s]", bar, eta)
+}
+
+// formatBytes formats a byte count, e.g. "340 KB" or "1.2 MB".
+func formatBytes(n float64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", n/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0f KB", n/(1<<10))
+	}
+	return fmt.Sprintf("%.0f B", n)
+}
+
+// speedLabel formats the playback rate, e.g. "1.5x" or "0.75x tape", and
+// is empty at normal speed.
+func speedLabel(speed float64, tape bool) string {
+	if speed == 0 || speed == 1 {
+		return ""
+	}
+	label := strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", speed), "0"), ".") + "x"
+	if tape {
+		label += " tape"
+	}
+	return label
+}
+
+// rateLabel formats the sample rate, e.g. "44.1 kHz", or "48 → 44.1 kHz"
+// when the track is being resampled.
+func rateLabel(source, output int) string {
+	if source <= 0 {
+		return ""
+	}
+	khz := func(hz int) string {
+		return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", float64(hz)/1000), "0"), ".")
+	}
+	if output <= 0 || output == source {
+		return khz(source) + " kHz"
+	}
+	return khz(source) + " → " + khz(output) + " kHz"
+}
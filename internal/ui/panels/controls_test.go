@@ -0,0 +1,154 @@
+package panels
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLabel(t *testing.T) {
+	tests := []struct {
+		name           string
+		source, output int
+		want           string
+	}{
+		{"unknown", 0, 44100, ""},
+		{"native", 44100, 44100, "44.1 kHz"},
+		{"whole khz", 48000, 48000, "48 kHz"},
+		{"resampled", 48000, 44100, "48 → 44.1 kHz"},
+		{"output unknown", 22050, 0, "22.05 kHz"},
+		{"three decimals", 11025, 44100, "11.025 → 44.1 kHz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rateLabel(tt.source, tt.output); got != tt.want {
+				t.Errorf("rateLabel(%d, %d) = %q, want %q", tt.source, tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpeedLabel(t *testing.T) {
+	tests := []struct {
+		speed float64
+		tape  bool
+		want  string
+	}{
+		{0, false, ""},
+		{1, true, ""},
+		{1.5, false, "1.5x"},
+		{0.75, true, "0.75x tape"},
+		{2, false, "2x"},
+	}
+	for _, tt := range tests {
+		if got := speedLabel(tt.speed, tt.tape); got != tt.want {
+			t.Errorf("speedLabel(%v, %v) = %q, want %q", tt.speed, tt.tape, got, tt.want)
+		}
+	}
+}
+
+func TestBufferLabel(t *testing.T) {
+	tests := []struct {
+		name               string
+		downloaded, needed int64
+		rate               float64
+		want               string
+	}{
+		{"quarter", 25_000, 100_000, 7_500, "[██░░░░░░ 0:10]"},
+		{"stalled", 50_000, 100_000, 0, "[████░░░░ stalled]"},
+		{"start reached", 100_000, 100_000, 50_000, "[████████ 0:00]"},
+		{"past start", 120_000, 100_000, 50_000, "[████████ 0:00]"},
+		{"needed unknown", 3 << 19, -1, 340 << 10, "[1.5 MB 340 KB/s]"},
+		{"needed unknown stalled", 512, -1, 0, "[512 B stalled]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bufferLabel(tt.downloaded, tt.needed, tt.rate); got != tt.want {
+				t.Errorf("bufferLabel(%d, %d, %v) = %q, want %q", tt.downloaded, tt.needed, tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSleepLabel(t *testing.T) {
+	tests := []struct {
+		left  time.Duration
+		album bool
+		want  string
+	}{
+		{0, false, ""},
+		{29*time.Minute + 45*time.Second, false, "sleep 29:45"},
+		{0, true, "sleep: end of album"},
+		{3 * time.Minute, true, "sleep 3:00"},
+	}
+	for _, tt := range tests {
+		if got := sleepLabel(tt.left, tt.album); got != tt.want {
+			t.Errorf("sleepLabel(%v, %v) = %q, want %q", tt.left, tt.album, got, tt.want)
+		}
+	}
+}
+
+func TestControlsViewWidth(t *testing.T) {
+	c := NewControls()
+	c.Width = 80
+	c.SourceRate = 48000
+	c.OutputRate = 44100
+	c.Speed = 1.25
+	for _, sleep := range []time.Duration{0, 90 * time.Minute} {
+		c.Sleep = sleep
+		for i, line := range strings.Split(c.View(), "\n") {
+			if got := AnsiVisLen(line); got != c.Width {
+				t.Errorf("sleep %v: line %d is %d wide, want %d", sleep, i, got, c.Width)
+			}
+		}
+	}
+}
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripAnsi(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+func TestLoopMarks(t *testing.T) {
+	c := NewControls()
+	c.Width = 80 // 76 timeline columns
+	c.Duration = 100 * time.Second
+	c.LoopA = 25 * time.Second
+	c.LoopB = 50 * time.Second
+
+	line := []rune(stripAnsi(strings.Split(c.View(), "\n")[1]))
+	if len(line) != c.Width {
+		t.Fatalf("line is %d wide, want %d", len(line), c.Width)
+	}
+	// Column i of the timeline is after "│ ".
+	if line[2+19] != '[' || line[2+38] != ']' {
+		t.Errorf("marks missing at columns 19 and 38: %q", string(line))
+	}
+
+	c.LoopA, c.LoopB = -1, -1
+	if got := c.loopColumn(c.LoopA, 76); got != -1 {
+		t.Errorf("unset loop point at column %d, want -1", got)
+	}
+}
+
+func TestRepeatMode(t *testing.T) {
+	var got []string
+	m := RepeatOff
+	for range 5 {
+		got = append(got, m.String())
+		m = m.Next()
+	}
+	if want := "off one album all off"; strings.Join(got, " ") != want {
+		t.Errorf("cycling from off went %q, want %q", strings.Join(got, " "), want)
+	}
+	for _, mode := range []RepeatMode{RepeatOff, RepeatOne, RepeatAlbum, RepeatAll} {
+		if got := ParseRepeat(mode.String()); got != mode {
+			t.Errorf("ParseRepeat(%q) = %v, want %v", mode.String(), got, mode)
+		}
+	}
+	if got := ParseRepeat("sometimes"); got != RepeatOff {
+		t.Errorf("ParseRepeat(%q) = %v, want off", "sometimes", got)
+	}
+}
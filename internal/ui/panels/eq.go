@@ -0,0 +1,174 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EQ is the equalizer overlay: one vertical slider per band.
+type EQ struct {
+	Labels []string  // band names, e.g. "31", "1k"
+	Gains  []float64 // dB per band
+	Band   int       // selected band
+	Preset string    // preset name, "" once adjusted by hand
+	MaxDB  float64
+}
+
+const (
+	eqColW  = 5 // columns per band
+	eqScale = 4 // width of the dB scale on the left
+	eqStep  = 3 // dB per slider row
+)
+
+func NewEQ(labels []string, gains []float64, preset string, maxDB float64) EQ {
+	g := make([]float64, len(labels))
+	copy(g, gains)
+	return EQ{Labels: labels, Gains: g, Preset: preset, MaxDB: maxDB}
+}
+
+func (e *EQ) Left() {
+	if e.Band > 0 {
+		e.Band--
+	}
+}
+
+func (e *EQ) Right() {
+	if e.Band < len(e.Gains)-1 {
+		e.Band++
+	}
+}
+
+// Adjust changes the selected band by db, which makes the setting custom.
+func (e *EQ) Adjust(db float64) {
+	g := e.Gains[e.Band] + db
+	g = max(-e.MaxDB, min(e.MaxDB, g))
+	if g != e.Gains[e.Band] {
+		e.Gains[e.Band] = g
+		e.Preset = ""
+	}
+}
+
+// SetPreset loads a named preset's gains.
+func (e *EQ) SetPreset(name string, gains []float64) {
+	for i := range e.Gains {
+		e.Gains[i] = 0
+		if i < len(gains) {
+			e.Gains[i] = gains[i]
+		}
+	}
+	e.Preset = name
+}
+
+// Width returns the overlay's width including borders.
+func (e EQ) Width() int {
+	return e.contentW() + 4
+}
+
+func (e EQ) contentW() int {
+	return eqScale + len(e.Labels)*eqColW
+}
+
+func (e EQ) View() string {
+	t := CurrentTheme()
+	contentW := e.contentW()
+	rows := int(e.MaxDB/eqStep)*2 + 1 // +max .. 0 .. -max
+
+	var b strings.Builder
+
+	// Top border with title
+	titleAnsi := BuildTitleGradient("Equalizer", t.TitleGrad1, t.TitleGrad2, t.TitleGrad3)
+	title := fmt.Sprintf(" %s\x1b[38;5;%sm ", titleAnsi, t.ActiveBorderColor)
+	remaining := contentW + 2 - 11 // " Equalizer " = 11 visible chars
+	leftPad := remaining / 2
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╭", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(title)
+	b.WriteString(FadeBorder(remaining-leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╮\x1b[0m\n", t.ActiveCornerColor))
+
+	total := rows + 6
+	row := 0
+	line := func(content string) {
+		writeBorderedLine(&b, t.ActiveBorderColor, t.ActiveFadeColor, content, contentW, row, total, true)
+		row++
+	}
+
+	preset := e.Preset
+	if preset == "" {
+		preset = "Custom"
+	}
+	line(fmt.Sprintf("\x1b[38;5;%smPreset: \x1b[38;5;%sm%s\x1b[0m", t.TextDim, t.ChatNameColor, preset))
+	line("")
+
+	// Sliders, top row is +MaxDB
+	for r := 0; r < rows; r++ {
+		level := e.MaxDB - float64(r*eqStep)
+		var l strings.Builder
+		if r == 0 || r == rows-1 || level == 0 {
+			l.WriteString(fmt.Sprintf("\x1b[38;5;%sm%+3.0f ", t.TextDim, level))
+		} else {
+			l.WriteString(strings.Repeat(" ", eqScale))
+		}
+		for i, g := range e.Gains {
+			color := t.TextColor
+			if i == e.Band {
+				color = t.ChatNameColor
+			}
+			filled := (level > 0 && g >= level-eqStep/2.0) ||
+				(level < 0 && g <= level+eqStep/2.0) ||
+				level == 0
+			switch {
+			case level == 0 && i == e.Band:
+				l.WriteString(fmt.Sprintf("\x1b[38;5;%sm ━━━ ", color))
+			case level == 0:
+				l.WriteString(fmt.Sprintf("\x1b[38;5;%sm ─── ", t.BorderColor))
+			case filled:
+				l.WriteString(fmt.Sprintf("\x1b[38;5;%sm ███ ", color))
+			default:
+				l.WriteString(fmt.Sprintf("\x1b[38;5;%sm  ·  ", t.FadeColor))
+			}
+		}
+		l.WriteString("\x1b[0m")
+		line(l.String())
+	}
+
+	// Band names and values
+	var names, values strings.Builder
+	names.WriteString(strings.Repeat(" ", eqScale))
+	values.WriteString(strings.Repeat(" ", eqScale))
+	for i, label := range e.Labels {
+		color := t.TextDim
+		if i == e.Band {
+			color = t.ChatNameColor
+		}
+		names.WriteString(fmt.Sprintf("\x1b[38;5;%sm%s", color, center(label, eqColW)))
+		values.WriteString(fmt.Sprintf("\x1b[38;5;%sm%s", color, center(fmt.Sprintf("%+.0f", e.Gains[i]), eqColW)))
+	}
+	line(names.String() + "\x1b[0m")
+	line(values.String() + "\x1b[0m")
+	line("")
+
+	key := func(k, label string) string {
+		return fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%sm%s\x1b[38;5;%sm] \x1b[38;5;%sm%s", t.HelpBracket, t.HelpKey, k, t.HelpBracket, t.TextDim, label)
+	}
+	line(strings.Join([]string{
+		key("←/→", "BAND"), key("↑/↓", "GAIN"), key("P", "PRESET"), key("0", "RESET"), key("E", "CLOSE"),
+	}, " ") + "\x1b[0m")
+
+	// Bottom border
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╰", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(contentW+2, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╯\x1b[0m", t.ActiveCornerColor))
+
+	return b.String()
+}
+
+// center pads s to w columns, centered.
+func center(s string, w int) string {
+	n := len([]rune(s))
+	if n >= w {
+		return s
+	}
+	left := (w - n) / 2
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", w-n-left)
+}
@@ -0,0 +1,29 @@
+package panels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEQViewWidth(t *testing.T) {
+	labels := []string{"31", "62", "125", "250", "500", "1k", "2k", "4k", "8k", "16k"}
+	e := NewEQ(labels, []float64{12, -12, 3}, "", 12)
+	for i, line := range strings.Split(e.View(), "\n") {
+		if got := AnsiVisLen(line); got != e.Width() {
+			t.Errorf("line %d is %d wide, want %d", i, got, e.Width())
+		}
+	}
+}
+
+func TestEQAdjust(t *testing.T) {
+	e := NewEQ([]string{"a", "b"}, nil, "Flat", 12)
+	e.Right()
+	e.Adjust(5)
+	e.Adjust(10)
+	if e.Gains[1] != 12 || e.Gains[0] != 0 {
+		t.Errorf("gains = %v, want [0 12]", e.Gains)
+	}
+	if e.Preset != "" {
+		t.Errorf("preset = %q after manual change, want custom", e.Preset)
+	}
+}
@@ -0,0 +1,137 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+)
+
+// QueueItem is a queued track as the queue overlay shows it.
+type QueueItem struct {
+	Title    string
+	Album    string
+	Duration time.Duration
+}
+
+// Queue is the play queue overlay: the tracks lined up to play next.
+type Queue struct {
+	Items    []QueueItem
+	Shuffled []QueueItem // what shuffle plays after the queue, shown below it
+	Cursor   int
+	Offset   int
+}
+
+const (
+	queueW    = 64 // content width
+	QueueRows = 12 // tracks shown at once
+)
+
+// SetItems replaces the listed tracks, keeping the cursor in range.
+func (q *Queue) SetItems(items []QueueItem) {
+	q.Items = items
+	q.Select(q.Cursor)
+}
+
+func (q *Queue) Up() {
+	q.Select(q.Cursor - 1)
+}
+
+func (q *Queue) Down() {
+	q.Select(q.Cursor + 1)
+}
+
+// Select moves the cursor to item i, clamped to the list, scrolling it
+// into view.
+func (q *Queue) Select(i int) {
+	q.Cursor = max(0, min(i, len(q.Items)-1))
+	if q.Cursor < q.Offset {
+		q.Offset = q.Cursor
+	} else if q.Cursor >= q.Offset+QueueRows {
+		q.Offset = q.Cursor - QueueRows + 1
+	}
+}
+
+// queueRow lays out an item as its title and album, the gap after them
+// and its length.
+func queueRow(item QueueItem) (text, gap, dur string) {
+	if item.Duration > 0 {
+		dur = data.FormatDuration(item.Duration)
+	}
+	text = item.Title
+	if item.Album != "" {
+		text += " · " + item.Album
+	}
+	text = truncate(text, queueW-4-len(dur)-1)
+	gap = strings.Repeat(" ", queueW-4-len([]rune(text))-len(dur))
+	return text, gap, dur
+}
+
+// Width returns the overlay's width including borders.
+func (q Queue) Width() int {
+	return queueW + 4
+}
+
+func (q Queue) View() string {
+	t := CurrentTheme()
+	var b strings.Builder
+
+	// Top border with title
+	titleAnsi := BuildTitleGradient("Up Next", t.TitleGrad1, t.TitleGrad2, t.TitleGrad3)
+	title := fmt.Sprintf(" %s\x1b[38;5;%sm ", titleAnsi, t.ActiveBorderColor)
+	remaining := queueW + 2 - 9 // " Up Next " = 9 visible chars
+	leftPad := remaining / 2
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╭", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(title)
+	b.WriteString(FadeBorder(remaining-leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╮\x1b[0m\n", t.ActiveCornerColor))
+
+	total := QueueRows + 2
+	row := 0
+	line := func(content string) {
+		writeBorderedLine(&b, t.ActiveBorderColor, t.ActiveFadeColor, content, queueW, row, total, true)
+		row++
+	}
+
+	selFg := t.SelectionFg
+	if selFg == "" {
+		selFg = "231"
+	}
+	for i := q.Offset; i < q.Offset+QueueRows; i++ {
+		if i >= len(q.Items) {
+			// Shuffle's picks follow the queue, dimmed and out of the cursor's reach
+			if s := i - len(q.Items); s < len(q.Shuffled) {
+				text, gap, dur := queueRow(q.Shuffled[s])
+				line(fmt.Sprintf("\x1b[38;5;%sm ~  %s%s%s\x1b[0m", t.TextDim, text, gap, dur))
+			} else if i == 0 {
+				line(fmt.Sprintf("\x1b[38;5;%smNothing queued: a adds a track or album, A plays it next.\x1b[0m", t.TextDim))
+			} else {
+				line("")
+			}
+			continue
+		}
+		text, gap, dur := queueRow(q.Items[i])
+		if i == q.Cursor {
+			line(fmt.Sprintf("\x1b[48;5;%sm\x1b[38;5;%sm%2d  %s%s%s\x1b[0m", t.SelectionBg, selFg, i+1, text, gap, dur))
+		} else {
+			line(fmt.Sprintf("\x1b[38;5;%sm%2d  \x1b[38;5;%sm%s%s\x1b[38;5;%sm%s\x1b[0m", t.TextDim, i+1, t.TextColor, text, gap, t.TextDim, dur))
+		}
+	}
+	line("")
+
+	key := func(k, label string) string {
+		return fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%sm%s\x1b[38;5;%sm] \x1b[38;5;%sm%s", t.HelpBracket, t.HelpKey, k, t.HelpBracket, t.TextDim, label)
+	}
+	line(strings.Join([]string{
+		key("ENTER", "PLAY"), key("⇧↑/↓", "MOVE"), key("D", "REMOVE"), key("C", "CLEAR"), key("U", "CLOSE"),
+	}, " ") + "\x1b[0m")
+
+	// Bottom border
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╰", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(queueW+2, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╯\x1b[0m", t.ActiveCornerColor))
+
+	return b.String()
+}
@@ -90,3 +90,55 @@ func writeBorderedLine(b *strings.Builder, borderColor, fadeColor, content strin
 			sideColor, content, strings.Repeat(" ", pad), sideColor))
 	}
 }
+
+// Overlay draws box over base with its top-left corner at column x, row y.
+// Styling in base is replayed after the box so the rest of each line keeps
+// its colors.
+func Overlay(base, box string, x, y int) string {
+	lines := strings.Split(base, "\n")
+	for i, boxLine := range strings.Split(box, "\n") {
+		row := y + i
+		if row < 0 || row >= len(lines) {
+			continue
+		}
+		head, _, _ := splitAnsi(lines[row], x)
+		if vis := AnsiVisLen(head); vis < x {
+			head += strings.Repeat(" ", x-vis)
+		}
+		_, tail, style := splitAnsi(lines[row], x+AnsiVisLen(boxLine))
+		lines[row] = head + "\x1b[0m" + boxLine + "\x1b[0m" + style + tail
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitAnsi splits s after n visible columns. style holds the escape
+// sequences in effect at the cut (everything since the last reset), so
+// writing it before tail restores tail's styling.
+func splitAnsi(s string, n int) (head, tail, style string) {
+	var esc, st strings.Builder
+	col := 0
+	inEscape := false
+	for i, r := range s {
+		if r == '\x1b' {
+			inEscape = true
+			esc.Reset()
+		}
+		if inEscape {
+			esc.WriteRune(r)
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+				if esc.String() == "\x1b[0m" {
+					st.Reset()
+				} else {
+					st.WriteString(esc.String())
+				}
+			}
+			continue
+		}
+		if col == n {
+			return s[:i], s[i:], st.String()
+		}
+		col++
+	}
+	return s, "", st.String()
+}
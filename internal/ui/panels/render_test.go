@@ -126,3 +126,26 @@ func TestWriteBorderedLine(t *testing.T) {
 		t.Error("padded writeBorderedLine should contain border chars")
 	}
 }
+
+func TestOverlay(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		box  string
+		x, y int
+		want string
+	}{
+		{"plain", "abcdef\nghijkl", "XY", 2, 1, "abcdef\ngh\x1b[0mXY\x1b[0mkl"},
+		{"past end pads", "ab", "X", 4, 0, "ab  \x1b[0mX\x1b[0m"},
+		{"off screen rows skipped", "ab", "X\nY", 0, 1, "ab"},
+		{"style replayed", "\x1b[31mabcd\x1b[0mef", "X", 1, 0, "\x1b[31ma\x1b[0mX\x1b[0m\x1b[31mcd\x1b[0mef"},
+		{"reset clears style", "\x1b[31mab\x1b[0mcdef", "X", 3, 0, "\x1b[31mab\x1b[0mc\x1b[0mX\x1b[0mef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlay(tt.base, tt.box, tt.x, tt.y); got != tt.want {
+				t.Errorf("Overlay() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchResult is a match as the search overlay lists it.
+type SearchResult struct {
+	Title string
+	Album string // the track's album; empty when the match is an album
+}
+
+// Search is the search overlay: a query and the albums and tracks it
+// matches, best first.
+type Search struct {
+	Query   string
+	Results []SearchResult
+	Cursor  int
+	Offset  int
+}
+
+const (
+	searchW    = 64 // content width
+	searchRows = 12 // results shown at once
+)
+
+// SetResults replaces the results and goes back to the best one.
+func (s *Search) SetResults(results []SearchResult) {
+	s.Results = results
+	s.Offset = 0
+	s.Select(0)
+}
+
+func (s *Search) Up() {
+	s.Select(s.Cursor - 1)
+}
+
+func (s *Search) Down() {
+	s.Select(s.Cursor + 1)
+}
+
+// Select moves the cursor to result i, clamped to the list, scrolling it
+// into view.
+func (s *Search) Select(i int) {
+	s.Cursor = max(0, min(i, len(s.Results)-1))
+	if s.Cursor < s.Offset {
+		s.Offset = s.Cursor
+	} else if s.Cursor >= s.Offset+searchRows {
+		s.Offset = s.Cursor - searchRows + 1
+	}
+}
+
+// Width returns the overlay's width including borders.
+func (s Search) Width() int {
+	return searchW + 4
+}
+
+func (s Search) View() string {
+	t := CurrentTheme()
+	var b strings.Builder
+
+	// Top border with title
+	titleAnsi := BuildTitleGradient("Search", t.TitleGrad1, t.TitleGrad2, t.TitleGrad3)
+	title := fmt.Sprintf(" %s\x1b[38;5;%sm ", titleAnsi, t.ActiveBorderColor)
+	remaining := searchW + 2 - 8 // " Search " = 8 visible chars
+	leftPad := remaining / 2
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╭", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(title)
+	b.WriteString(FadeBorder(remaining-leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╮\x1b[0m\n", t.ActiveCornerColor))
+
+	total := searchRows + 3
+	row := 0
+	line := func(content string) {
+		writeBorderedLine(&b, t.ActiveBorderColor, t.ActiveFadeColor, content, searchW, row, total, true)
+		row++
+	}
+
+	// Query, keeping its end in view as it grows
+	query := []rune(s.Query)
+	if over := len(query) - (searchW - 3); over > 0 {
+		query = query[over:]
+	}
+	line(fmt.Sprintf("\x1b[38;5;%sm/ \x1b[38;5;231m%s\x1b[38;5;%sm▌\x1b[0m", t.HelpKey, string(query), t.HelpKey))
+	line("")
+
+	selFg := t.SelectionFg
+	if selFg == "" {
+		selFg = "231"
+	}
+	for i := s.Offset; i < s.Offset+searchRows; i++ {
+		if i >= len(s.Results) {
+			switch {
+			case i > 0:
+				line("")
+			case s.Query == "":
+				line(fmt.Sprintf("\x1b[38;5;%smType to search every album and track.\x1b[0m", t.TextDim))
+			default:
+				line(fmt.Sprintf("\x1b[38;5;%smNo matches.\x1b[0m", t.TextDim))
+			}
+			continue
+		}
+		r := s.Results[i]
+		kind, text := "♪", r.Title+" · "+r.Album
+		if r.Album == "" {
+			kind, text = "◉", r.Title
+		}
+		text = truncate(text, searchW-3)
+		pad := strings.Repeat(" ", searchW-3-len([]rune(text)))
+		if i == s.Cursor {
+			line(fmt.Sprintf("\x1b[48;5;%sm\x1b[38;5;%sm%s  %s%s\x1b[0m", t.SelectionBg, selFg, kind, text, pad))
+		} else {
+			line(fmt.Sprintf("\x1b[38;5;%sm%s  \x1b[38;5;%sm%s\x1b[0m", t.TextDim, kind, t.TextColor, text))
+		}
+	}
+
+	key := func(k, label string) string {
+		return fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%sm%s\x1b[38;5;%sm] \x1b[38;5;%sm%s", t.HelpBracket, t.HelpKey, k, t.HelpBracket, t.TextDim, label)
+	}
+	line(strings.Join([]string{
+		key("ENTER", "GO TO"), key("ALT+ENTER", "PLAY"), key("↑/↓", "MOVE"), key("ESC", "CLOSE"),
+	}, " ") + "\x1b[0m")
+
+	// Bottom border
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╰", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(searchW+2, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╯\x1b[0m", t.ActiveCornerColor))
+
+	return b.String()
+}
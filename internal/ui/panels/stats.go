@@ -0,0 +1,121 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/history"
+)
+
+// StatsRows is how many tracks, albums and recent plays the stats overlay
+// lists.
+const StatsRows = 5
+
+const statsW = 64 // content width
+
+// Stats is the listening stats overlay.
+type Stats struct {
+	Summary history.Stats
+}
+
+// Width returns the overlay's width including borders.
+func (s Stats) Width() int {
+	return statsW + 4
+}
+
+func (s Stats) View() string {
+	t := CurrentTheme()
+	var b strings.Builder
+	sum := s.Summary
+
+	// Top border with title
+	titleAnsi := BuildTitleGradient("Listening", t.TitleGrad1, t.TitleGrad2, t.TitleGrad3)
+	title := fmt.Sprintf(" %s\x1b[38;5;%sm ", titleAnsi, t.ActiveBorderColor)
+	remaining := statsW + 2 - 11 // " Listening " = 11 visible chars
+	leftPad := remaining / 2
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╭", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(title)
+	b.WriteString(FadeBorder(remaining-leftPad, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╮\x1b[0m\n", t.ActiveCornerColor))
+
+	total := 3*(StatsRows+2) + 3
+	row := 0
+	line := func(content string) {
+		writeBorderedLine(&b, t.ActiveBorderColor, t.ActiveFadeColor, content, statsW, row, total, true)
+		row++
+	}
+	dim := func(text string) string {
+		return fmt.Sprintf("\x1b[38;5;%sm%s", t.TextDim, text)
+	}
+	bright := func(text string) string {
+		return fmt.Sprintf("\x1b[38;5;%sm%s", t.TextColor, text)
+	}
+	// entry is one row of a list: text on the left, a figure on the right
+	entry := func(i int, text, figure string) {
+		text = truncate(text, statsW-4-len(figure)-1)
+		gap := strings.Repeat(" ", statsW-4-len([]rune(text))-len(figure))
+		line(fmt.Sprintf("%s%s%s%s\x1b[0m", dim(fmt.Sprintf("%2d  ", i+1)), bright(text), gap, dim(figure)))
+	}
+	section := func(heading string, n int, row func(i int)) {
+		line(fmt.Sprintf("\x1b[38;5;%sm%s\x1b[0m", t.HelpKey, heading))
+		for i := range StatsRows {
+			if i < n {
+				row(i)
+			} else if i == 0 {
+				line(dim("Nothing yet.") + "\x1b[0m")
+			} else {
+				line("")
+			}
+		}
+		line("")
+	}
+
+	line(fmt.Sprintf("%s%s  %s%s  %s%s\x1b[0m",
+		bright(listenTime(sum.Seconds)), dim(" listened"),
+		bright(fmt.Sprint(sum.Plays)), dim(fmt.Sprintf(" plays, %d skipped", sum.Skipped)),
+		bright(fmt.Sprint(sum.Streak)), dim(fmt.Sprintf(" day streak (best %d)", sum.LongestStreak))))
+	line("")
+
+	section("Most played tracks", len(sum.TopTracks), func(i int) {
+		c := sum.TopTracks[i]
+		entry(i, c.Title+" · "+c.Album, plays(c.Plays))
+	})
+	section("Most played albums", len(sum.TopAlbums), func(i int) {
+		c := sum.TopAlbums[i]
+		entry(i, c.Title, plays(c.Plays))
+	})
+	section("Recently played", len(sum.Recent), func(i int) {
+		p := sum.Recent[i]
+		entry(i, p.Title+" · "+p.Album, p.Start.Local().Format("Jan 2 15:04"))
+	})
+
+	key := func(k, label string) string {
+		return fmt.Sprintf("\x1b[38;5;%sm[\x1b[38;5;%sm%s\x1b[38;5;%sm] \x1b[38;5;%sm%s", t.HelpBracket, t.HelpKey, k, t.HelpBracket, t.TextDim, label)
+	}
+	line(key("H", "CLOSE") + "\x1b[0m")
+
+	// Bottom border
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╰", t.ActiveCornerColor))
+	b.WriteString(FadeBorder(statsW+2, FadeDashes, t.ActiveFadeColor, t.ActiveBorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╯\x1b[0m", t.ActiveCornerColor))
+
+	return b.String()
+}
+
+// listenTime formats a total like "12h 5m".
+func listenTime(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func plays(n int) string {
+	if n == 1 {
+		return "1 play"
+	}
+	return fmt.Sprintf("%d plays", n)
+}
@@ -254,6 +254,18 @@ func CurrentTheme() *Theme {
 	return &themes[themeIdx]
 }
 
+// SetTheme switches to the theme called name and reports whether there is
+// one.
+func SetTheme(name string) bool {
+	for i := range themes {
+		if themes[i].Name == name {
+			themeIdx = i
+			return true
+		}
+	}
+	return false
+}
+
 // CycleTheme advances to the next theme and returns it.
 func CycleTheme() *Theme {
 	themeIdx = (themeIdx + 1) % len(themes)
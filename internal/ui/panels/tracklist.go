@@ -19,6 +19,10 @@ type TrackList struct {
 	Focused      bool
 	Color        string // album color for track text
 	AnimTick     int    // animation counter, advanced externally
+
+	// Marks reports whether a track is a favorite and its rating, for the
+	// ♥ and stars beside it. nil shows no marks.
+	Marks func(url string) (favorite bool, rating int)
 }
 
 func NewTrackList() TrackList {
@@ -73,6 +77,20 @@ func (t *TrackList) Bottom() {
 	}
 }
 
+// Select moves the cursor to track i, scrolling it into view.
+func (t *TrackList) Select(i int) {
+	if t.Album == nil || i < 0 || i >= len(t.Album.Tracks) {
+		return
+	}
+	t.Cursor = i
+	vis := t.visibleTracks()
+	if t.Cursor < t.Offset {
+		t.Offset = t.Cursor
+	} else if t.Cursor >= t.Offset+vis {
+		t.Offset = t.Cursor - vis + 1
+	}
+}
+
 func (t *TrackList) visibleTracks() int {
 	n := t.Height - 2 // border (2)
 	if n < 1 {
@@ -146,8 +164,30 @@ func (tl TrackList) View() string {
 
 			num := fmt.Sprintf("%02d", i+1)
 
+			// A ♥ before favorites; stars and the length, once it's known,
+			// right-aligned after the title
+			favorite, rating := false, 0
+			if tl.Marks != nil {
+				favorite, rating = tl.Marks(track.URL)
+			}
+			lead, leadSel := "  ", "  "
+			if favorite {
+				lead = fmt.Sprintf(" \x1b[38;5;%sm♥", t.ChatNameColor)
+				leadSel = " ♥"
+			}
+			right := strings.Repeat("★", rating)
+			if track.Duration > 0 {
+				right = strings.TrimSpace(right + " " + data.FormatDuration(track.Duration))
+			}
 			maxTitle := contentW - 5 // "  NN " or "  XX " prefix
+			if right != "" {
+				maxTitle -= len([]rune(right)) + 2 // a space either side
+			}
 			titleStr := truncate(track.Title, maxTitle)
+			gap := ""
+			if right != "" {
+				gap = strings.Repeat(" ", max(maxTitle-len([]rune(titleStr)), 0)+1)
+			}
 
 			var line string
 			isPlaying := i == tl.PlayingTrack
@@ -161,25 +201,25 @@ func (tl TrackList) View() string {
 			if isPlaying && isSelected {
 				s1 := spinFrames[(tl.AnimTick*7)%len(spinFrames)]
 				s2 := spinFrames[(tl.AnimTick*7+3)%len(spinFrames)]
-				visText := fmt.Sprintf("  %c%c %s", s1, s2, titleStr)
+				visText := fmt.Sprintf("%s%c%c %s%s%s", leadSel, s1, s2, titleStr, gap, right)
 				pad := contentW - len([]rune(visText))
 				if pad < 0 {
 					pad = 0
 				}
-				line = fmt.Sprintf("\x1b[48;5;%sm  \x1b[38;5;%sm%c%c \x1b[38;5;%sm%s%s\x1b[0m", t.SelectionBg, selFg, s1, s2, selFg, titleStr, strings.Repeat(" ", pad))
+				line = fmt.Sprintf("\x1b[48;5;%sm\x1b[38;5;%sm%s%c%c %s%s%s%s\x1b[0m", t.SelectionBg, selFg, leadSel, s1, s2, titleStr, gap, right, strings.Repeat(" ", pad))
 			} else if isPlaying {
 				s1 := spinFrames[(tl.AnimTick*7)%len(spinFrames)]
 				s2 := spinFrames[(tl.AnimTick*7+3)%len(spinFrames)]
-				line = fmt.Sprintf("  \x1b[38;5;%sm%c%c \x1b[38;5;231m%s\x1b[0m", numColor, s1, s2, titleStr)
+				line = fmt.Sprintf("%s\x1b[38;5;%sm%c%c \x1b[38;5;231m%s%s\x1b[38;5;%sm%s\x1b[0m", lead, numColor, s1, s2, titleStr, gap, numColor, right)
 			} else if isSelected {
-				visText := fmt.Sprintf("  %s %s", num, titleStr)
+				visText := fmt.Sprintf("%s%s %s%s%s", leadSel, num, titleStr, gap, right)
 				pad := contentW - len([]rune(visText))
 				if pad < 0 {
 					pad = 0
 				}
-				line = fmt.Sprintf("\x1b[48;5;%sm  \x1b[38;5;%sm%s \x1b[38;5;%sm%s%s\x1b[0m", t.SelectionBg, selFg, num, selFg, titleStr, strings.Repeat(" ", pad))
+				line = fmt.Sprintf("\x1b[48;5;%sm\x1b[38;5;%sm%s%s %s%s%s%s\x1b[0m", t.SelectionBg, selFg, leadSel, num, titleStr, gap, right, strings.Repeat(" ", pad))
 			} else {
-				line = fmt.Sprintf("  \x1b[38;5;%sm%s \x1b[38;5;%sm%s\x1b[0m", numColor, num, textColor, titleStr)
+				line = fmt.Sprintf("%s\x1b[38;5;%sm%s \x1b[38;5;%sm%s%s\x1b[38;5;%sm%s\x1b[0m", lead, numColor, num, textColor, titleStr, gap, numColor, right)
 			}
 
 			writeBorderedLine(&b, borderColor, fadeColor, line, contentW, lineIdx, contentLines, false)
@@ -193,9 +233,21 @@ func (tl TrackList) View() string {
 		lineIdx++
 	}
 
-	// Bottom border
+	// Bottom border, with the album's total length at the right
 	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╰", cornerColor))
-	b.WriteString(FadeBorder(contentW, FadeDashes, fadeColor, borderColor))
+	total := ""
+	if tl.Album != nil {
+		if d, ok := tl.Album.Duration(); ok {
+			total = data.FormatDuration(d)
+		}
+	}
+	if total != "" && contentW > len(total)+6 {
+		b.WriteString(FadeBorder(contentW-len(total)-4, FadeDashes, fadeColor, borderColor))
+		b.WriteString(fmt.Sprintf("\x1b[38;5;%sm %s ", t.TextDim, total))
+		b.WriteString(fmt.Sprintf("\x1b[38;5;%sm──", fadeColor))
+	} else {
+		b.WriteString(FadeBorder(contentW, FadeDashes, fadeColor, borderColor))
+	}
 	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╯\x1b[0m", cornerColor))
 
 	return b.String()
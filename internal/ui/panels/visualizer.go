@@ -0,0 +1,244 @@
+package panels
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dangerous-person/dopogoto/internal/theme"
+)
+
+// Visualizer draws the player's spectrum as bars, either as a panel of its
+// own or over the video.
+type Visualizer struct {
+	Width  int
+	Height int
+
+	levels      []float64 // per bar, 0-1, smoothed
+	bottom, top theme.HSL // gradient for themes without their own hue
+}
+
+const (
+	visBarW  = 2    // columns per bar
+	visGap   = 1    // columns between bars
+	visDecay = 0.04 // how far a bar falls per tick
+)
+
+var visBlocks = []string{"", "▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+func NewVisualizer() Visualizer {
+	v := Visualizer{}
+	v.Reseed()
+	return v
+}
+
+// Reseed picks a new random gradient.
+func (v *Visualizer) Reseed() {
+	v.bottom, v.top = theme.RandomHSLPair()
+}
+
+// Bands returns how many bars fit the panel.
+func (v Visualizer) Bands() int {
+	return max(0, (v.Width-2+visGap)/(visBarW+visGap))
+}
+
+// Update feeds one frame of band levels. Bars jump up and fall back slowly;
+// nil lets them all fall.
+func (v *Visualizer) Update(levels []float64) {
+	if n := v.Bands(); len(v.levels) != n {
+		v.levels = make([]float64, n)
+	}
+	for i := range v.levels {
+		l := v.levels[i] - visDecay
+		if i < len(levels) && levels[i] > l {
+			l = levels[i]
+		}
+		v.levels[i] = max(0, l)
+	}
+}
+
+// gradient returns one color per row, bottom row first. Mono and tinted
+// themes get shades of their own color so the bars match the video.
+func (v Visualizer) gradient(rows int) []int {
+	t := CurrentTheme()
+	bottom, top := v.bottom, v.top
+	switch {
+	case t.Name == "Mono":
+		bottom, top = theme.HSL{L: 30}, theme.HSL{L: 95}
+	case t.VideoTintHue > 0:
+		bottom = theme.HSL{H: t.VideoTintHue, S: t.VideoTintSat, L: 25}
+		top = theme.HSL{H: t.VideoTintHue, S: t.VideoTintSat, L: 75}
+	}
+	return theme.GenerateGradient(bottom, top, rows)
+}
+
+// cell returns the glyph for bar b at row (0 is the bottom) in a stack of
+// rows, or "" where the bar doesn't reach.
+func (v Visualizer) cell(b, row, rows int) string {
+	eighths := int(v.levels[b]*float64(rows*8)+0.5) - row*8
+	return visBlocks[max(0, min(8, eighths))]
+}
+
+// barsLeft is the padding that centers the bars in contentW columns.
+func (v Visualizer) barsLeft(contentW int) int {
+	used := len(v.levels)*(visBarW+visGap) - visGap
+	return max(0, (contentW-used)/2)
+}
+
+// View renders the bars in a bordered panel the size of the video panel.
+func (v Visualizer) View() string {
+	t := CurrentTheme()
+	contentW := v.Width - 2
+	contentH := v.Height - 2
+	if contentW < 1 || contentH < 1 {
+		return ""
+	}
+	colors := v.gradient(contentH)
+	left := v.barsLeft(contentW)
+
+	var b strings.Builder
+
+	titleAnsi := BuildTitleGradient("Spectrum", t.TitleGrad1, t.TitleGrad2, t.TitleGrad3)
+	title := fmt.Sprintf(" %s\x1b[38;5;%sm ", titleAnsi, t.BorderColor)
+	remaining := max(0, contentW-10) // " Spectrum " = 10 visible chars
+	leftPad := remaining / 2
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╭", t.CornerColor))
+	b.WriteString(FadeBorder(leftPad, FadeDashes, t.FadeColor, t.BorderColor))
+	b.WriteString(title)
+	b.WriteString(FadeBorder(remaining-leftPad, FadeDashes, t.FadeColor, t.BorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╮\x1b[0m\n", t.CornerColor))
+
+	for i := 0; i < contentH; i++ {
+		row := contentH - 1 - i
+		var l strings.Builder
+		l.WriteString(strings.Repeat(" ", left))
+		l.WriteString(fmt.Sprintf("\x1b[38;5;%dm", colors[row]))
+		for bar := range v.levels {
+			if bar > 0 {
+				l.WriteString(strings.Repeat(" ", visGap))
+			}
+			if c := v.cell(bar, row, contentH); c != "" {
+				l.WriteString(strings.Repeat(c, visBarW))
+			} else {
+				l.WriteString(strings.Repeat(" ", visBarW))
+			}
+		}
+		l.WriteString("\x1b[0m")
+		writeBorderedLine(&b, t.BorderColor, t.FadeColor, l.String(), contentW, i, contentH, false)
+	}
+
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╰", t.CornerColor))
+	b.WriteString(FadeBorder(contentW, FadeDashes, t.FadeColor, t.BorderColor))
+	b.WriteString(fmt.Sprintf("\x1b[38;5;%sm╯\x1b[0m", t.CornerColor))
+
+	return b.String()
+}
+
+// Over draws the bars across the bottom half of a rendered panel of the
+// same size, such as the video, leaving the panel visible between them.
+func (v Visualizer) Over(frame string) string {
+	contentW := v.Width - 2
+	contentH := v.Height - 2
+	rows := contentH / 2
+	if contentW < 1 || rows < 1 {
+		return frame
+	}
+	colors := v.gradient(rows)
+	left := 1 + v.barsLeft(contentW) // +1 for the border
+
+	lines := strings.Split(frame, "\n")
+	for row := 0; row < rows; row++ {
+		y := contentH - row // the bottom content line is at contentH
+		if y >= len(lines) {
+			continue
+		}
+		bars := make(map[int]string) // first column -> the bar's cells
+		for bar := range v.levels {
+			if c := v.cell(bar, row, rows); c != "" {
+				bars[left+bar*(visBarW+visGap)] = fmt.Sprintf("\x1b[38;5;%dm%s", colors[row], strings.Repeat(c, visBarW))
+			}
+		}
+		if len(bars) > 0 {
+			lines[y] = overBars(lines[y], bars)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// overBars draws bars, keyed by first column, over line in a single pass,
+// restoring line's styling after each bar.
+func overBars(line string, bars map[int]string) string {
+	var b, esc strings.Builder // esc is the escape being read
+	var style sgr
+	col, skip, restore := 0, 0, false
+	inEscape := false
+	for _, r := range line {
+		if r == '\x1b' {
+			inEscape = true
+			esc.Reset()
+		}
+		if inEscape {
+			esc.WriteRune(r)
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+				style.apply(esc.String())
+				if skip == 0 {
+					b.WriteString(esc.String())
+				}
+			}
+			continue
+		}
+		if bar, ok := bars[col]; ok {
+			b.WriteString("\x1b[0m" + bar + "\x1b[0m")
+			skip, restore = visBarW, true
+		}
+		if skip > 0 {
+			skip--
+		} else {
+			if restore {
+				b.WriteString(style.String())
+				restore = false
+			}
+			b.WriteRune(r)
+		}
+		col++
+	}
+	return b.String()
+}
+
+// sgr is the styling in effect partway along a line: the last foreground
+// and background escapes and any other attributes set since the last
+// reset, so restoring it writes no more than it takes.
+type sgr struct {
+	fg, bg string
+	attrs  []string
+}
+
+// apply updates the styling with esc. Escapes other than SGR are ignored.
+func (s *sgr) apply(esc string) {
+	params, ok := strings.CutPrefix(esc, "\x1b[")
+	if !ok || !strings.HasSuffix(params, "m") {
+		return
+	}
+	params = strings.TrimSuffix(params, "m")
+	first, rest, _ := strings.Cut(params, ";")
+	n, err := strconv.Atoi(first)
+	switch {
+	case params == "" || (err == nil && n == 0):
+		*s = sgr{}
+		if rest != "" {
+			s.apply("\x1b[" + rest + "m")
+		}
+	case err == nil && (n >= 30 && n <= 39 || n >= 90 && n <= 97):
+		s.fg = esc
+	case err == nil && (n >= 40 && n <= 49 || n >= 100 && n <= 107):
+		s.bg = esc
+	case !slices.Contains(s.attrs, esc):
+		s.attrs = append(s.attrs, esc)
+	}
+}
+
+func (s sgr) String() string {
+	return strings.Join(s.attrs, "") + s.fg + s.bg
+}
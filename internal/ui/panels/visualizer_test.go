@@ -0,0 +1,118 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestVisualizerUpdate(t *testing.T) {
+	v := NewVisualizer()
+	v.Width, v.Height = 14, 10 // 4 bars
+	v.Update([]float64{1, 0.5, 0, 0.2})
+	v.Update([]float64{0.2, 0.8})
+	want := []float64{1 - visDecay, 0.8, 0, 0.2 - visDecay}
+	for i := range want {
+		if d := v.levels[i] - want[i]; d > 1e-9 || d < -1e-9 {
+			t.Errorf("levels = %v, want %v", v.levels, want)
+			break
+		}
+	}
+	for i := 0; i < 50; i++ {
+		v.Update(nil)
+	}
+	for _, l := range v.levels {
+		if l != 0 {
+			t.Errorf("levels = %v after falling, want all 0", v.levels)
+			break
+		}
+	}
+}
+
+func TestVisualizerSize(t *testing.T) {
+	v := NewVisualizer()
+	v.Width, v.Height = 61, 12
+	v.Update([]float64{1, 0.9, 0.5, 0.3, 0.1, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0.7})
+
+	view := v.View()
+	if got := strings.Count(view, "\n") + 1; got != v.Height {
+		t.Errorf("view has %d lines, want %d", got, v.Height)
+	}
+	frame := make([]string, v.Height)
+	for i, line := range strings.Split(view, "\n") {
+		if got := AnsiVisLen(line); got != v.Width {
+			t.Errorf("view line %d is %d wide, want %d", i, got, v.Width)
+		}
+		frame[i] = strings.Repeat("x", v.Width)
+	}
+
+	over := strings.Split(v.Over(strings.Join(frame, "\n")), "\n")
+	for i, line := range over {
+		if got := AnsiVisLen(line); got != v.Width {
+			t.Errorf("overlaid line %d is %d wide, want %d", i, got, v.Width)
+		}
+	}
+	if !strings.Contains(over[v.Height-2], "█") || strings.Contains(over[1], "█") {
+		t.Error("bars should cover the bottom half only")
+	}
+}
+
+func TestVisualizerOverKeepsFrame(t *testing.T) {
+	v := NewVisualizer()
+	v.Width, v.Height = 30, 10
+	v.Update([]float64{1, 0, 0.6, 0.3, 1, 0.9})
+
+	frame := make([]string, v.Height)
+	for i := range frame {
+		frame[i] = "\x1b[38;5;33m" + strings.Repeat("ab", v.Width/2) + "\x1b[0m"
+	}
+	base := strings.Join(frame, "\n")
+
+	// Bar by bar with Overlay, as the bars look stacked on the frame
+	want := base
+	rows := (v.Height - 2) / 2
+	colors := v.gradient(rows)
+	left := 1 + v.barsLeft(v.Width-2)
+	for row := 0; row < rows; row++ {
+		for bar := range v.levels {
+			if c := v.cell(bar, row, rows); c != "" {
+				box := fmt.Sprintf("\x1b[38;5;%dm%s", colors[row], strings.Repeat(c, visBarW))
+				want = Overlay(want, box, left+bar*(visBarW+visGap), v.Height-2-row)
+			}
+		}
+	}
+	if got := v.Over(base); stripAnsi(got) != stripAnsi(want) {
+		t.Errorf("Over drew\n%s\nwant\n%s", stripAnsi(got), stripAnsi(want))
+	}
+	for i, line := range strings.Split(v.Over(base), "\n") {
+		// Frame cells after a bar get the frame's color back
+		if strings.Contains(line, "█") && !strings.Contains(line, "\x1b[0m\x1b[38;5;33m") {
+			t.Errorf("line %d doesn't restore the frame's color after a bar: %q", i, line)
+		}
+	}
+}
+
+func TestOverBarsRestoresStyleInEffect(t *testing.T) {
+	const bar = "\x1b[38;5;9m██"
+	tests := []struct {
+		name, line, want string
+	}{
+		{"last foreground only", "\x1b[38;5;1mab\x1b[38;5;2mcd\x1b[38;5;3mxxxx", "\x1b[38;5;3m"},
+		{"foreground and background", "\x1b[48;5;4m\x1b[38;5;1mab\x1b[38;5;2mcdxxxx", "\x1b[38;5;2m\x1b[48;5;4m"},
+		{"attributes kept", "\x1b[1m\x1b[38;5;1m\x1b[1mabcdxxxx", "\x1b[1m\x1b[38;5;1m"},
+		{"nothing after a reset", "\x1b[38;5;1mab\x1b[0mcdxxxx", ""},
+		{"reset and set in one", "\x1b[38;5;1mab\x1b[0;38;5;5mcdxxxx", "\x1b[38;5;5m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := overBars(tt.line, map[int]string{4: bar})
+			_, after, ok := strings.Cut(got, bar+"\x1b[0m")
+			if !ok {
+				t.Fatalf("no bar in %q", got)
+			}
+			if restored, _, _ := strings.Cut(after, "x"); restored != tt.want {
+				t.Errorf("restored %q after the bar, want %q", restored, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/playlist"
+	"github.com/dangerous-person/dopogoto/internal/queue"
+	"github.com/dangerous-person/dopogoto/internal/tags"
+)
+
+// playlistHelp lists the /pl commands.
+const playlistHelp = "/pl new NAME, /pl add [NAME], /pl remove, /pl delete [NAME], /pl import FILE, /pl export FILE"
+
+// playlistCommand runs a /pl (or /playlist) chat command.
+func (a *App) playlistCommand(arg string) {
+	verb, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	rest = strings.TrimSpace(rest)
+	switch verb {
+	case "new":
+		a.newPlaylist(rest)
+	case "add":
+		a.addToPlaylist(rest)
+	case "remove", "rm":
+		a.removeFromPlaylist()
+	case "delete":
+		a.deletePlaylist(rest)
+	case "import":
+		a.importPlaylist(rest)
+	case "export":
+		a.exportPlaylist(rest)
+	default:
+		a.playlistMsg(playlistHelp)
+	}
+}
+
+func (a *App) playlistMsg(format string, args ...any) {
+	a.chat.AddLocalMessage("[playlist]", fmt.Sprintf(format, args...))
+}
+
+// playlistBase is the album list index of the first playlist; playlists
+// follow the catalog and local albums.
+func (a *App) playlistBase() int {
+	return len(a.albumList.Albums) - len(a.playlists)
+}
+
+// findPlaylist returns the index of the playlist called name, or -1.
+func (a *App) findPlaylist(name string) int {
+	return slices.IndexFunc(a.playlists, func(p playlist.Playlist) bool {
+		return strings.EqualFold(p.Name, name)
+	})
+}
+
+// selectedPlaylist returns the index of the playlist open in the track
+// list, or -1 if it's showing an album.
+func (a *App) selectedPlaylist() int {
+	i := a.albumList.Cursor - a.playlistBase()
+	if i < 0 || i >= len(a.playlists) {
+		return -1
+	}
+	return i
+}
+
+// addPlaylist saves p and appends it to the album list, reporting false if
+// it couldn't be saved.
+func (a *App) addPlaylist(p playlist.Playlist) bool {
+	if err := playlist.Save(playlist.Dir(), p); err != nil {
+		log.Printf("save playlist: %v", err)
+		a.playlistMsg("couldn't save %s: %v", p.Name, err)
+		return false
+	}
+	a.playlists = append(a.playlists, p)
+	a.albumList.Albums = append(a.albumList.Albums, p.Album())
+	a.repointTracks()
+	return true
+}
+
+// savePlaylist writes playlist i and mirrors its tracks into its album.
+func (a *App) savePlaylist(i int) {
+	p := a.playlists[i]
+	a.albumList.Albums[a.playlistBase()+i].Tracks = slices.Clone(p.Tracks)
+	a.repointTracks()
+	if err := playlist.Save(playlist.Dir(), p); err != nil {
+		log.Printf("save playlist: %v", err)
+		a.playlistMsg("couldn't save %s: %v", p.Name, err)
+	}
+}
+
+// repointTracks points the track list back at the selected album after the
+// album list changes underneath it, keeping the cursor where it can.
+func (a *App) repointTracks() {
+	sel := a.albumList.SelectedAlbum()
+	if sel == nil {
+		return
+	}
+	a.trackList.Album = sel
+	a.trackList.Select(min(a.trackList.Cursor, len(sel.Tracks)-1))
+}
+
+func (a *App) newPlaylist(name string) {
+	if name == "" {
+		a.playlistMsg("name it: /pl new NAME")
+		return
+	}
+	if i := a.findPlaylist(name); i >= 0 {
+		a.playlistTarget = a.playlists[i].Name
+		a.playlistMsg("%s already exists; /pl add puts tracks in it", a.playlistTarget)
+		return
+	}
+	if a.addPlaylist(playlist.Playlist{Name: name}) {
+		a.playlistTarget = name
+		a.playlistMsg("made %s; /pl add puts the selected track in it", name)
+	}
+}
+
+// addToPlaylist adds the track list's selected track, or failing that the
+// playing one, to the named playlist (made if need be) or the last one
+// used.
+func (a *App) addToPlaylist(name string) {
+	if name == "" {
+		name = a.playlistTarget
+	}
+	if name == "" {
+		a.playlistMsg("which playlist? /pl add NAME")
+		return
+	}
+	track := a.trackList.SelectedTrack()
+	if track == nil && a.currentAlbumIdx >= 0 {
+		track = &a.albumList.Albums[a.currentAlbumIdx].Tracks[a.currentTrackIdx]
+	}
+	if track == nil {
+		a.playlistMsg("select a track first")
+		return
+	}
+	t := *track
+	i := a.findPlaylist(name)
+	if i < 0 {
+		if !a.addPlaylist(playlist.Playlist{Name: name}) {
+			return
+		}
+		i = len(a.playlists) - 1
+	}
+	a.playlists[i].Tracks = append(a.playlists[i].Tracks, t)
+	a.playlistTarget = a.playlists[i].Name
+	a.savePlaylist(i)
+	a.playlistMsg("added %s to %s", t.Title, a.playlistTarget)
+}
+
+// removeFromPlaylist drops the selected track from the playlist open in
+// the track list.
+func (a *App) removeFromPlaylist() {
+	i := a.selectedPlaylist()
+	ti := a.trackList.Cursor
+	if i < 0 || ti >= len(a.playlists[i].Tracks) {
+		a.playlistMsg("open a playlist and select the track to remove")
+		return
+	}
+	title := a.playlists[i].Tracks[ti].Title
+	a.playlists[i].Tracks = slices.Delete(a.playlists[i].Tracks, ti, ti+1)
+	a.savePlaylist(i)
+	a.trackRemoved(a.playlistBase()+i, ti)
+	a.playlistMsg("removed %s from %s", title, a.playlists[i].Name)
+}
+
+// deletePlaylist deletes the named playlist, or the open one.
+func (a *App) deletePlaylist(name string) {
+	i := a.selectedPlaylist()
+	if name != "" {
+		i = a.findPlaylist(name)
+	}
+	if i < 0 {
+		a.playlistMsg("no playlist to delete; /pl delete NAME")
+		return
+	}
+	p := a.playlists[i]
+	if err := playlist.Delete(playlist.Dir(), p.Name); err != nil && !os.IsNotExist(err) {
+		a.playlistMsg("couldn't delete %s: %v", p.Name, err)
+		return
+	}
+	idx := a.playlistBase() + i
+	a.playlists = slices.Delete(a.playlists, i, i+1)
+	a.albumList.Albums = slices.Delete(a.albumList.Albums, idx, idx+1)
+	if strings.EqualFold(a.playlistTarget, p.Name) {
+		a.playlistTarget = ""
+	}
+	a.albumRemoved(idx)
+	a.playlistMsg("deleted %s", p.Name)
+}
+
+// importPlaylist reads an M3U or PLS file into a new playlist.
+func (a *App) importPlaylist(path string) {
+	if path == "" {
+		a.playlistMsg("/pl import FILE (.m3u, .m3u8 or .pls)")
+		return
+	}
+	p, err := playlist.Import(expandHome(path))
+	if err != nil {
+		a.playlistMsg("couldn't import %s: %v", path, err)
+		return
+	}
+	base := p.Name
+	for n := 2; a.findPlaylist(p.Name) >= 0; n++ {
+		p.Name = fmt.Sprintf("%s (%d)", base, n)
+	}
+	for j := range p.Tracks {
+		a.fillTrack(&p.Tracks[j])
+	}
+	if a.addPlaylist(p) {
+		a.playlistMsg("imported %d tracks as %s", len(p.Tracks), p.Name)
+	}
+}
+
+// exportPlaylist writes the selected album or playlist to an M3U or PLS
+// file.
+func (a *App) exportPlaylist(path string) {
+	sel := a.albumList.SelectedAlbum()
+	if path == "" || sel == nil {
+		a.playlistMsg("/pl export FILE (.m3u, .m3u8 or .pls) saves the selected album or playlist")
+		return
+	}
+	path = expandHome(path)
+	if err := playlist.Export(path, sel.Tracks); err != nil {
+		a.playlistMsg("couldn't export %s: %v", sel.Title, err)
+		return
+	}
+	a.playlistMsg("exported %s to %s", sel.Title, path)
+}
+
+// fillTrack gives an imported track the catalog's title, and its length
+// from the catalog or its tags, when it's known.
+func (a *App) fillTrack(t *data.Track) {
+	for _, album := range a.albumList.Albums[:a.playlistBase()] {
+		for _, known := range album.Tracks {
+			if known.URL == t.URL {
+				t.Title = known.Title
+				t.Duration = max(t.Duration, known.Duration)
+				return
+			}
+		}
+	}
+	if t.Duration > 0 {
+		return
+	}
+	if info, ok := a.tags.Get(t.URL); ok {
+		t.Duration = info.Duration
+	} else if path, ok := strings.CutPrefix(t.URL, "file://"); ok {
+		if info, err := tags.ReadFile(path); err == nil {
+			t.Duration = info.Duration
+		}
+	}
+}
+
+// albumRemoved keeps the queue and the playing track in step after album
+// idx leaves the album list.
+func (a *App) albumRemoved(idx int) {
+	a.queue.Remap(func(e queue.Entry) (queue.Entry, bool) {
+		if e.Album == idx {
+			return e, false
+		}
+		if e.Album > idx {
+			e.Album--
+		}
+		return e, true
+	})
+	switch {
+	case a.currentAlbumIdx == idx:
+		// Play on, but there's no next in album order to go to
+		a.currentAlbumIdx, a.currentTrackIdx = -1, -1
+		a.hasUpcoming = false
+		a.player.SetNext("", "")
+	case a.currentAlbumIdx > idx:
+		a.currentAlbumIdx--
+	}
+	if a.albumList.Cursor >= len(a.albumList.Albums) || a.albumList.Cursor > idx {
+		a.albumList.Select(a.albumList.Cursor - 1)
+	}
+	a.syncTracks()
+	a.queueChanged()
+}
+
+// trackRemoved keeps the queue and the playing track in step after track
+// idx leaves album.
+func (a *App) trackRemoved(album, idx int) {
+	a.queue.Remap(func(e queue.Entry) (queue.Entry, bool) {
+		if e.Album != album || e.Track < idx {
+			return e, true
+		}
+		if e.Track == idx {
+			return e, false
+		}
+		e.Track--
+		return e, true
+	})
+	if a.currentAlbumIdx == album && a.currentTrackIdx >= idx {
+		// If the playing track itself was removed, this leaves the one
+		// that followed it up next.
+		a.currentTrackIdx--
+		if a.albumList.Cursor == album {
+			a.trackList.PlayingTrack = a.currentTrackIdx
+		}
+	}
+	a.queueChanged()
+}
+
+// expandHome expands a leading ~ to the home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + rest
+		}
+	}
+	return path
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"github.com/dangerous-person/dopogoto/internal/queue"
+	"github.com/dangerous-person/dopogoto/internal/ui/panels"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// enqueue lines up the selected track, or with the album list focused the
+// whole album, at the end of the queue or, with next set, at its front.
+func (a *App) enqueue(next bool) {
+	var entries []queue.Entry
+	switch a.focus {
+	case focusAlbums:
+		if sel := a.albumList.SelectedAlbum(); sel != nil {
+			for i := range sel.Tracks {
+				entries = append(entries, queue.Entry{Album: a.albumList.Cursor, Track: i})
+			}
+		}
+	case focusTracks:
+		if a.trackList.SelectedTrack() != nil {
+			entries = append(entries, queue.Entry{Album: a.albumList.Cursor, Track: a.trackList.Cursor})
+		}
+	}
+	if next {
+		// Front-loaded in reverse so the album keeps its order
+		for i := len(entries) - 1; i >= 0; i-- {
+			a.queue.PlayNext(entries[i])
+		}
+	} else {
+		for _, e := range entries {
+			a.queue.Add(e)
+		}
+	}
+	a.queueChanged()
+}
+
+// takeQueued drops the front of the queue if it's the track about to play.
+func (a *App) takeQueued(albumIdx, trackIdx int) {
+	if e, ok := a.queue.Peek(); ok && e == (queue.Entry{Album: albumIdx, Track: trackIdx}) {
+		a.queue.Pop()
+		a.syncQueue()
+	}
+}
+
+// queueChanged refreshes the prefetched upcoming track, and with it the
+// overlay, after the queue is edited.
+func (a *App) queueChanged() {
+	a.queueNext()
+}
+
+// syncQueue points the overlay at the queue's tracks and, on shuffle, the
+// shuffle bag's that follow them.
+func (a *App) syncQueue() {
+	item := func(e queue.Entry) panels.QueueItem {
+		album := &a.albumList.Albums[e.Album]
+		track := album.Tracks[e.Track]
+		return panels.QueueItem{Title: track.Title, Album: album.Title, Duration: track.Duration}
+	}
+	entries := a.queue.Entries()
+	items := make([]panels.QueueItem, len(entries))
+	for i, e := range entries {
+		items[i] = item(e)
+	}
+	a.queuePanel.SetItems(items)
+
+	a.queuePanel.Shuffled = nil
+	if a.controls.Shuffle && a.currentAlbumIdx >= 0 {
+		key, urls, at := a.shufflePool()
+		for _, url := range a.shuffleBag.Upcoming(key, urls, panels.QueueRows) {
+			a.queuePanel.Shuffled = append(a.queuePanel.Shuffled, item(at[url]))
+		}
+	}
+}
+
+func (a *App) handleQueueKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	cur := a.queuePanel.Cursor
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return a, a.quit()
+	case "u", "esc":
+		a.showQueue = false
+	case "up", "k":
+		a.queuePanel.Up()
+	case "down", "j":
+		a.queuePanel.Down()
+	case "shift+up", "K":
+		if a.queue.Move(cur, cur-1) {
+			a.queuePanel.Up()
+			a.queueChanged()
+		}
+	case "shift+down", "J":
+		if a.queue.Move(cur, cur+1) {
+			a.queuePanel.Down()
+			a.queueChanged()
+		}
+	case "d", "x", "delete", "backspace":
+		a.queue.Remove(cur)
+		a.queueChanged()
+	case "c":
+		a.queue.Clear()
+		a.queueChanged()
+	case "enter":
+		if cur >= a.queue.Len() {
+			return a, nil
+		}
+		e := a.queue.Entries()[cur]
+		a.queue.Remove(cur)
+		a.syncQueue()
+		a.showQueue = false
+		return a, a.playTrack(e.Album, e.Track)
+	case " ":
+		a.togglePause()
+	}
+	return a, nil
+}
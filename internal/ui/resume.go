@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"log"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dangerous-person/dopogoto/internal/config"
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/ui/panels"
+)
+
+// resumePoint is the track the last session stopped on, offered on launch.
+type resumePoint struct {
+	albumIdx, trackIdx int
+	pos                time.Duration
+	seeking            bool // the track is loading and seeks to pos once it starts
+}
+
+// restoreState puts back the volume, shuffle, repeat, theme and genre
+// filter from the last session and selects the track it stopped on, ready
+// to resume.
+func (a *App) restoreState() {
+	st, ok := config.LoadState()
+	if !ok {
+		return
+	}
+	a.player.SetVolumeLevel(st.Volume)
+	a.controls.Volume = a.player.VolumeLevel()
+	a.controls.Shuffle = st.Shuffle
+	if !st.Shuffle {
+		a.controls.Repeat = panels.ParseRepeat(string(st.Repeat))
+	}
+	panels.SetTheme(st.Theme)
+	if data.IsGenre(st.Genre) {
+		a.setGenre(st.Genre)
+	}
+	if !a.restoreTrack(st) && len(a.libraryDirs) > 0 {
+		a.unresumed = &st
+	}
+}
+
+// restoreTrack selects the track st stopped on and offers to resume it. It
+// returns false if the track isn't listed.
+func (a *App) restoreTrack(st config.State) bool {
+	for ai, album := range a.albumList.Albums {
+		if album.Title != st.Album {
+			continue
+		}
+		for ti, track := range album.Tracks {
+			if track.URL != st.Track {
+				continue
+			}
+			a.reveal(ai)
+			a.albumList.Select(ai)
+			a.syncTracks()
+			a.trackList.Select(ti)
+			if a.focus == focusAlbums {
+				a.switchFocus()
+			}
+			pos := time.Duration(st.Position) * time.Second
+			a.resume = &resumePoint{albumIdx: ai, trackIdx: ti, pos: pos}
+			msg := "press enter to play " + track.Title
+			if pos > 0 {
+				msg = "press enter to pick up " + track.Title + " at " + data.FormatDuration(pos)
+			}
+			a.chat.AddLocalMessage("[resume]", msg)
+			return true
+		}
+	}
+	return false
+}
+
+// saveState records the playing track, how far into it playback got and
+// the other settings restoreState puts back.
+func (a *App) saveState() {
+	st := config.State{
+		Volume:  a.player.VolumeLevel(),
+		Shuffle: a.controls.Shuffle,
+		Repeat:  config.Repeat(a.controls.Repeat.String()),
+		Theme:   panels.CurrentTheme().Name,
+		Genre:   a.albumList.Genre,
+	}
+	albumIdx, trackIdx := a.currentAlbumIdx, a.currentTrackIdx
+	switch {
+	case albumIdx >= 0:
+		if a.controls.State == panels.StatePlaying || a.controls.State == panels.StatePaused {
+			st.Position = int(a.player.Position().Seconds())
+		}
+	case a.resume != nil:
+		// Never got round to resuming: offer the same spot next time.
+		albumIdx, trackIdx = a.resume.albumIdx, a.resume.trackIdx
+		st.Position = int(a.resume.pos.Seconds())
+	}
+	if albumIdx >= 0 {
+		album := &a.albumList.Albums[albumIdx]
+		st.Album = album.Title
+		st.Track = album.Tracks[trackIdx].URL
+	}
+	if err := config.SaveState(st); err != nil {
+		log.Printf("save state: %v", err)
+	}
+}
+
+// resumeStarting is called as a track is asked for: playing the offered
+// track arms the seek to where it left off, anything else drops the offer.
+func (a *App) resumeStarting(albumIdx, trackIdx int) {
+	if a.resume == nil {
+		return
+	}
+	if albumIdx == a.resume.albumIdx && trackIdx == a.resume.trackIdx && a.resume.pos > 0 {
+		a.resume.seeking = true
+		return
+	}
+	a.resume = nil
+}
+
+// resumeStarted seeks the resumed track to where it left off once it has
+// started playing.
+func (a *App) resumeStarted() {
+	if a.resume == nil || !a.resume.seeking {
+		return
+	}
+	if a.currentAlbumIdx == a.resume.albumIdx && a.currentTrackIdx == a.resume.trackIdx {
+		a.player.Seek(a.resume.pos)
+		a.controls.Position = a.resume.pos
+	}
+	a.resume = nil
+}
+
+// quit records the play under way, saves where playback got to and
+// shuts down.
+func (a *App) quit() tea.Cmd {
+	a.endListen(false)
+	a.saveState()
+	a.player.Close()
+	a.chatClient.Stop()
+	return tea.Quit
+}
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dangerous-person/dopogoto/internal/search"
+	"github.com/dangerous-person/dopogoto/internal/ui/panels"
+)
+
+// searchLimit caps how many matches the search overlay lists.
+const searchLimit = 100
+
+// openSearch indexes every album and track, bar the Favorites copies, and
+// shows the search overlay with the last query.
+func (a *App) openSearch() {
+	a.searchItems = a.searchItems[:0]
+	for ai, album := range a.albumList.Albums {
+		if ai == favoritesAlbum {
+			continue
+		}
+		a.searchItems = append(a.searchItems, search.Item{Text: album.Title, Album: ai, Track: -1})
+		for ti, track := range album.Tracks {
+			a.searchItems = append(a.searchItems, search.Item{Text: track.Title, Album: ai, Track: ti})
+		}
+	}
+	a.runSearch()
+	a.showSearch = true
+}
+
+// runSearch ranks the matches for the query as it stands.
+func (a *App) runSearch() {
+	a.searchMatches = search.Rank(a.searchPanel.Query, a.searchItems, searchLimit)
+	results := make([]panels.SearchResult, len(a.searchMatches))
+	for i, m := range a.searchMatches {
+		results[i].Title = m.Text
+		if m.Track >= 0 {
+			results[i].Album = a.albumList.Albums[m.Album].Title
+		}
+	}
+	a.searchPanel.SetResults(results)
+}
+
+// goToMatch closes the search and points the album and track lists at the
+// selected match, playing it if play is set.
+func (a *App) goToMatch(play bool) tea.Cmd {
+	if len(a.searchMatches) == 0 {
+		return nil
+	}
+	m := a.searchMatches[a.searchPanel.Cursor]
+	a.showSearch = false
+	a.reveal(m.Album)
+	a.albumList.Select(m.Album)
+	a.syncTracks()
+	if m.Track < 0 {
+		a.setFocus(focusAlbums)
+		if play && len(a.albumList.Albums[m.Album].Tracks) > 0 {
+			return a.playTrack(m.Album, 0)
+		}
+		return nil
+	}
+	a.trackList.Select(m.Track)
+	a.setFocus(focusTracks)
+	if play {
+		return a.playTrack(m.Album, m.Track)
+	}
+	return nil
+}
+
+func (a *App) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return a, a.quit()
+	case "esc":
+		a.showSearch = false
+	case "enter":
+		return a, a.goToMatch(false)
+	case "alt+enter":
+		return a, a.goToMatch(true)
+	case "up", "ctrl+p":
+		a.searchPanel.Up()
+	case "down", "ctrl+n":
+		a.searchPanel.Down()
+	case "backspace":
+		if q := []rune(a.searchPanel.Query); len(q) > 0 {
+			a.searchPanel.Query = string(q[:len(q)-1])
+			a.runSearch()
+		}
+	case "ctrl+u":
+		a.searchPanel.Query = ""
+		a.runSearch()
+	case " ":
+		a.searchPanel.Query += " "
+		a.runSearch()
+	default:
+		if msg.Type == tea.KeyRunes && !msg.Alt {
+			a.searchPanel.Query += string(msg.Runes)
+			a.runSearch()
+		}
+	}
+	return a, nil
+}
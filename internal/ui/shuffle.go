@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"log"
+	"slices"
+
+	"github.com/dangerous-person/dopogoto/internal/config"
+	"github.com/dangerous-person/dopogoto/internal/queue"
+	"github.com/dangerous-person/dopogoto/internal/ui/panels"
+)
+
+// shuffleScopes are what shuffle can draw from, in the order S steps
+// through them.
+var shuffleScopes = []string{"all", "genre", "album"}
+
+// shufflePool returns the tracks shuffle draws from for the playing
+// track, by URL, with where each is in the album list, and a key naming
+// the pool for the shuffle bag. Favorites is left out unless it's the
+// album in scope, as its tracks are all in other albums too.
+func (a *App) shufflePool() (key string, urls []string, at map[string]queue.Entry) {
+	cur := a.currentAlbumIdx
+	albums := a.albumList.Albums
+	var in func(i int) bool
+	switch a.settings.Shuffle {
+	case "album":
+		key = "album:" + albums[cur].Title
+		in = func(i int) bool { return i == cur }
+	case "genre":
+		genre := a.albumList.Genre
+		if genre == "" {
+			genre = albums[cur].Genre
+		}
+		key = "genre:" + genre
+		in = func(i int) bool { return i != favoritesAlbum && albums[i].Genre == genre }
+	default:
+		key = "all:" + a.albumList.Genre
+		in = func(i int) bool { return i != favoritesAlbum && a.albumList.Shows(i) }
+	}
+
+	at = map[string]queue.Entry{}
+	for i, album := range albums {
+		if !in(i) {
+			continue
+		}
+		for j, t := range album.Tracks {
+			if _, dup := at[t.URL]; !dup {
+				at[t.URL] = queue.Entry{Album: i, Track: j}
+				urls = append(urls, t.URL)
+			}
+		}
+	}
+	return key, urls, at
+}
+
+// shuffleNext returns the next track from the shuffle bag.
+func (a *App) shuffleNext() (albumIdx, trackIdx int, ok bool) {
+	key, urls, at := a.shufflePool()
+	url, ok := a.shuffleBag.Peek(key, urls)
+	if !ok {
+		return 0, 0, false
+	}
+	e := at[url]
+	return e.Album, e.Track, true
+}
+
+// shufflePlayed takes a track that has started out of the shuffle bag if
+// it was the next one due.
+func (a *App) shufflePlayed(url string) {
+	if !a.controls.Shuffle {
+		return
+	}
+	a.shuffleBag.Played(url)
+	if err := a.shuffleBag.Save(); err != nil {
+		log.Printf("save shuffle: %v", err)
+	}
+}
+
+// cycleShuffleScope steps what shuffle draws from and turns shuffle on.
+func (a *App) cycleShuffleScope() {
+	i := slices.Index(shuffleScopes, a.settings.Shuffle)
+	a.settings.Shuffle = shuffleScopes[(i+1)%len(shuffleScopes)]
+	if err := config.Save(a.settings); err != nil {
+		log.Printf("save settings: %v", err)
+	}
+	a.controls.Shuffle = true
+	a.controls.Repeat = panels.RepeatOff
+	a.queueNext()
+}
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dangerous-person/dopogoto/internal/ui/panels"
+)
+
+// sleepFade is how long the music fades out before the sleep timer stops it.
+const sleepFade = 30 * time.Second
+
+// sleepTimers are the steps the z key cycles through after off; 0 means the
+// end of the album.
+var sleepTimers = []time.Duration{15 * time.Minute, 30 * time.Minute, 60 * time.Minute, 0}
+
+// sleepTimer stops playback at a set time or at the end of the album.
+type sleepTimer struct {
+	on     bool
+	at     time.Time // when it goes off, unless album
+	album  bool
+	fading bool
+	step   int // position in sleepTimers for the z key, -1 when set otherwise
+}
+
+// setSleep starts a timer for d, or until the end of the album when album
+// is true, replacing any running one.
+func (a *App) setSleep(d time.Duration, album bool, step int) {
+	a.cancelSleep()
+	a.sleep = sleepTimer{on: true, at: time.Now().Add(d), album: album, step: step}
+	a.queueNext() // don't run on past the album
+	a.syncSleep()
+}
+
+// cancelSleep turns the timer off and undoes a fade in progress.
+func (a *App) cancelSleep() {
+	if a.sleep.fading {
+		a.player.CancelFade()
+	}
+	wasAlbum := a.sleep.album
+	a.sleep = sleepTimer{}
+	if wasAlbum {
+		a.queueNext()
+	}
+	a.syncSleep()
+}
+
+// cycleSleep steps the timer through off, 15, 30 and 60 minutes and the end
+// of the album.
+func (a *App) cycleSleep() {
+	next := 0
+	if a.sleep.on {
+		next = a.sleep.step + 1
+	}
+	if next >= len(sleepTimers) || (a.sleep.on && a.sleep.step < 0) {
+		a.cancelSleep()
+		return
+	}
+	a.setSleep(sleepTimers[next], sleepTimers[next] == 0, next)
+}
+
+// sleepCommand handles "/sleep 30m", "/sleep 45" (minutes), "/sleep album"
+// and "/sleep off" typed in the chat box.
+func (a *App) sleepCommand(arg string) {
+	arg = strings.TrimSpace(arg)
+	switch arg {
+	case "off", "":
+		a.cancelSleep()
+		return
+	case "album":
+		a.setSleep(0, true, -1)
+		return
+	}
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		if m, merr := strconv.Atoi(arg); merr == nil {
+			d, err = time.Duration(m)*time.Minute, nil
+		}
+	}
+	if err != nil || d <= 0 {
+		a.chat.AddLocalMessage("[sleep]", fmt.Sprintf("can't read %q, try /sleep 30m, /sleep album or /sleep off", arg))
+		return
+	}
+	a.setSleep(d, false, -1)
+}
+
+// lastOfAlbum reports whether the playing track ends its album.
+func (a *App) lastOfAlbum() bool {
+	return a.currentAlbumIdx >= 0 && a.currentTrackIdx == len(a.albumList.Albums[a.currentAlbumIdx].Tracks)-1
+}
+
+// sleepLeft returns the time until the timer goes off. ok is false while
+// it's waiting for the album's last track, whose end isn't known yet.
+func (a *App) sleepLeft() (left time.Duration, ok bool) {
+	if !a.sleep.album {
+		return time.Until(a.sleep.at), true
+	}
+	if !a.lastOfAlbum() || a.controls.Duration <= 0 {
+		return 0, false
+	}
+	left = a.controls.Duration - a.player.Position()
+	if speed := a.player.Speed(); speed > 0 {
+		left = time.Duration(float64(left) / speed)
+	}
+	return left, true
+}
+
+// tickSleep starts the fade once the timer is close to going off.
+func (a *App) tickSleep() tea.Cmd {
+	if !a.sleep.on {
+		return nil
+	}
+	a.syncSleep()
+	left, ok := a.sleepLeft()
+	if !ok || a.sleep.fading || left > sleepFade {
+		return nil
+	}
+	if a.controls.State != panels.StatePlaying {
+		if left <= 0 {
+			return a.sleepDone()
+		}
+		return nil
+	}
+	a.sleep.fading = true
+	a.player.FadeOut(left)
+	return nil
+}
+
+// sleepDone ends the timer once the music has stopped, quitting if the
+// settings say so.
+func (a *App) sleepDone() tea.Cmd {
+	a.cancelSleep()
+	if a.controls.State == panels.StatePlaying {
+		a.controls.State = panels.StatePaused
+	}
+	if a.settings.SleepQuit {
+		return a.quit()
+	}
+	a.chat.AddLocalMessage("[sleep]", "good night")
+	return nil
+}
+
+// syncSleep shows the timer in the Now Playing panel.
+func (a *App) syncSleep() {
+	a.controls.Sleep = 0
+	a.controls.SleepAlbum = a.sleep.on && a.sleep.album
+	if !a.sleep.on {
+		return
+	}
+	if left, ok := a.sleepLeft(); ok {
+		// Round up so the last second reads 0:01, not 0:00.
+		a.controls.Sleep = max(left, 0).Truncate(time.Second) + time.Second
+	}
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/tags"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tagBatch is how many remote tracks have their tags fetched at once.
+const tagBatch = 8
+
+// tagTimeout bounds each header fetch.
+const tagTimeout = 10 * time.Second
+
+// tagsMsg carries the tags read for a batch of tracks, and the URLs still
+// to fetch.
+type tagsMsg struct {
+	infos map[string]tags.Info
+	rest  []string
+}
+
+// applyTags fills in track lengths from the tag store, copying each
+// album's tracks so the catalog itself is left untouched. It returns the
+// remote tracks the store has nothing for.
+func applyTags(albums []data.Album, store *tags.Store) (missing []string) {
+	for i := range albums {
+		albums[i].Tracks = slices.Clone(albums[i].Tracks)
+		for j := range albums[i].Tracks {
+			track := &albums[i].Tracks[j]
+			if track.Duration > 0 || !strings.HasPrefix(track.URL, "http") {
+				continue
+			}
+			if info, ok := store.Get(track.URL); ok {
+				track.Duration = info.Duration
+			} else {
+				missing = append(missing, track.URL)
+			}
+		}
+	}
+	return missing
+}
+
+// fetchTags reads the tags of the next batch of urls from their headers.
+// Tracks that can't be read are left out, to be tried again next launch.
+func fetchTags(urls []string) tea.Cmd {
+	if len(urls) == 0 {
+		return nil
+	}
+	batch, rest := urls[:min(tagBatch, len(urls))], urls[min(tagBatch, len(urls)):]
+	return func() tea.Msg {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		infos := make(map[string]tags.Info, len(batch))
+		for _, url := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), tagTimeout)
+				defer cancel()
+				if info, err := tags.Fetch(ctx, url); err == nil {
+					mu.Lock()
+					infos[url] = info
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		return tagsMsg{infos: infos, rest: rest}
+	}
+}
+
+// gotTags stores a batch of tags, shows their lengths and moves on to the
+// next batch.
+func (a *App) gotTags(msg tagsMsg) tea.Cmd {
+	for url, info := range msg.infos {
+		a.tags.Put(url, info)
+	}
+	if len(msg.infos) > 0 {
+		a.tags.Save()
+	}
+	for i := range a.albumList.Albums {
+		for j := range a.albumList.Albums[i].Tracks {
+			track := &a.albumList.Albums[i].Tracks[j]
+			if info, ok := msg.infos[track.URL]; ok && track.Duration == 0 {
+				track.Duration = info.Duration
+			}
+		}
+	}
+	return fetchTags(msg.rest)
+}
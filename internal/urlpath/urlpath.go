@@ -0,0 +1,23 @@
+// Package urlpath escapes the track URLs in the catalog, which are written
+// with plain spaces and punctuation in their paths.
+package urlpath
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Encode properly encodes a URL that may contain spaces in the path
+func Encode(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	// Encode each path segment
+	parts := strings.Split(u.Path, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	u.RawPath = strings.Join(parts, "/")
+	return u.String()
+}
@@ -1,30 +1,63 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/dangerous-person/dopogoto/internal/data"
+	"github.com/dangerous-person/dopogoto/internal/history"
 	"github.com/dangerous-person/dopogoto/internal/ui"
 )
 
 var version = "0.1.7"
 
 func main() {
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "--version", "-v":
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		if err := stats(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var library []string
+	for i := 1; i < len(os.Args); i++ {
+		switch arg := os.Args[i]; {
+		case arg == "--version" || arg == "-v":
 			fmt.Printf("dopogoto %s\n", version)
 			return
-		case "--help", "-h":
+		case arg == "--help" || arg == "-h":
 			fmt.Println("Dopo Goto — terminal music and video player")
 			fmt.Println("https://github.com/dangerous-person/dopogoto")
+			fmt.Println()
+			fmt.Println("  --library DIR   also list the music in DIR (repeatable)")
+			fmt.Println()
+			fmt.Println("  stats           show listening stats")
+			fmt.Println("  stats --json    print them, and every play, as JSON")
 			return
+		case arg == "--library" || strings.HasPrefix(arg, "--library="):
+			dir, ok := strings.CutPrefix(arg, "--library=")
+			if !ok {
+				dir = ""
+				if i+1 < len(os.Args) {
+					i++
+					dir = os.Args[i]
+				}
+			}
+			if dir == "" {
+				fmt.Fprintln(os.Stderr, "Error: --library needs a folder, e.g. --library ~/Music")
+				os.Exit(1)
+			}
+			library = append(library, dir)
 		}
 	}
 
-	app := ui.NewApp(version)
+	app := ui.NewApp(version, library)
 
 	p := tea.NewProgram(
 		app,
@@ -40,3 +73,42 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// stats prints the listening stats from the play history, as a summary or,
+// with --json, as JSON along with every recorded play.
+func stats(args []string) error {
+	plays, err := history.Load(history.DefaultPath())
+	if err != nil {
+		return err
+	}
+	sum := history.Summarize(plays, time.Now(), 10)
+
+	if len(args) > 0 && args[0] == "--json" {
+		if plays == nil {
+			plays = []history.Play{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			history.Stats
+			History []history.Play `json:"history"`
+		}{sum, plays})
+	}
+
+	listened := time.Duration(sum.Seconds) * time.Second
+	fmt.Printf("Listened %s over %d plays (%d finished, %d skipped)\n", data.FormatDuration(listened), sum.Plays, sum.Completed, sum.Skipped)
+	fmt.Printf("Streak %d days, best %d\n", sum.Streak, sum.LongestStreak)
+	fmt.Println("\nMost played tracks")
+	for i, c := range sum.TopTracks {
+		fmt.Printf("%3d. %s · %s (%d)\n", i+1, c.Title, c.Album, c.Plays)
+	}
+	fmt.Println("\nMost played albums")
+	for i, c := range sum.TopAlbums {
+		fmt.Printf("%3d. %s (%d)\n", i+1, c.Title, c.Plays)
+	}
+	fmt.Println("\nRecently played")
+	for _, p := range sum.Recent {
+		fmt.Printf("  %s  %s · %s\n", p.Start.Local().Format("Jan 2 15:04"), p.Title, p.Album)
+	}
+	return nil
+}